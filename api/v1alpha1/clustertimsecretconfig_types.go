@@ -0,0 +1,48 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterTimSecretConfigSpec defines a Vault configuration usable from several namespaces
+type ClusterTimSecretConfigSpec struct {
+	TimSecretConfigSpec `json:",inline"`
+
+	// NamespaceSelector selects the namespaces whose TimSecrets may use this config
+	// An empty selector ({}) selects every namespace
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// AllowedNamespaces lists namespaces whose TimSecrets may use this config
+	// References from namespaces that are neither listed nor selected are denied
+	// +optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+
+// ClusterTimSecretConfig is the Schema for cluster-wide Vault configuration.
+// ServiceAccounts, Secrets and ConfigMaps referenced without a namespace are
+// looked up in the operator's cluster resource namespace.
+type ClusterTimSecretConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterTimSecretConfigSpec `json:"spec,omitempty"`
+	Status TimSecretConfigStatus      `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterTimSecretConfigList contains a list of ClusterTimSecretConfig
+type ClusterTimSecretConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterTimSecretConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterTimSecretConfig{}, &ClusterTimSecretConfigList{})
+}
@@ -0,0 +1,16 @@
+package v1alpha1
+
+// SecretKeySelector selects a key of a Kubernetes Secret
+type SecretKeySelector struct {
+	// Name is the name of the Secret
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the Secret. It must be empty or the
+	// namespace of the referencing resource (the operator's cluster resource
+	// namespace for ClusterTimSecretConfigs); Secrets in other namespaces are refused
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key is the key in the Secret's data
+	Key string `json:"key"`
+}
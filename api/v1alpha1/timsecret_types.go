@@ -1,17 +1,24 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // TimSecretSpec defines the desired state of TimSecret
 type TimSecretSpec struct {
+	// VaultConfigRef references the TimSecretConfig or ClusterTimSecretConfig to use
+	// Takes precedence over VaultConfig
+	// +optional
+	VaultConfigRef *VaultConfigReference `json:"vaultConfigRef,omitempty"`
+
 	// VaultConfig is the name of the TimSecretConfig to use
 	// +optional
 	VaultConfig string `json:"vaultConfig,omitempty"`
 
 	// VaultConfigNamespace is the namespace of the TimSecretConfig
 	// If not specified, uses the TimSecret's namespace
+	// Deprecated: use a ClusterTimSecretConfig to share a configuration between namespaces
 	// +optional
 	VaultConfigNamespace string `json:"vaultConfigNamespace,omitempty"`
 
@@ -20,15 +27,60 @@ type TimSecretSpec struct {
 	VaultURL string `json:"vaultURL,omitempty"`
 
 	// VaultToken is the authentication token for Vault (direct value, overrides VaultConfig)
+	// Deprecated: use TokenSecretRef instead
 	// +optional
 	VaultToken string `json:"vaultToken,omitempty"`
 
-	// VaultPath is the path in Vault where secrets are stored
+	// TokenSecretRef references the Secret key holding the Vault token
+	// (direct value, used with VaultURL and overrides VaultConfig)
+	// +optional
+	TokenSecretRef *SecretKeySelector `json:"tokenSecretRef,omitempty"`
+
+	// VaultNamespace is the Vault Enterprise namespace used for login and reads
+	// Overrides the VaultNamespace of the TimSecretConfig
+	// +optional
+	VaultNamespace string `json:"vaultNamespace,omitempty"`
+
+	// VaultPath is the path in Vault where secrets are stored. KV v2 secrets
+	// can be given by their logical path (secret/myapp) or their API path
+	// (secret/data/myapp)
 	VaultPath string `json:"vaultPath"`
 
+	// Version pins the version of the secret at VaultPath. Only supported by
+	// KV v2; the latest version is read when unset
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Version int `json:"version,omitempty"`
+
+	// Sources lists further Vault paths merged into the Secret. Sources are
+	// read in order after VaultPath
+	// +optional
+	Sources []VaultSource `json:"sources,omitempty"`
+
+	// ConflictPolicy controls keys found in more than one source with different
+	// values: Error fails the sync, FirstWins keeps the value of the earliest
+	// source and LastWins the value of the latest
+	// +kubebuilder:validation:Enum=Error;FirstWins;LastWins
+	// +optional
+	// +kubebuilder:default="Error"
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+
+	// Data selects individual Vault keys into Secret keys. When Data or
+	// DataFrom is set, only the selected keys are written
+	// +optional
+	Data []SecretDataEntry `json:"data,omitempty"`
+
+	// DataFrom selects and renames Vault keys by rules
+	// +optional
+	DataFrom []SecretDataFromRule `json:"dataFrom,omitempty"`
+
 	// SecretName is the name of the Kubernetes Secret to create
 	SecretName string `json:"secretName"`
 
+	// Target describes the type and keys of the generated Secret
+	// +optional
+	Target *SecretTarget `json:"target,omitempty"`
+
 	// DeploymentName is the name of the Deployment to restart when secret changes
 	// +optional
 	DeploymentName string `json:"deploymentName,omitempty"`
@@ -43,6 +95,266 @@ type TimSecretSpec struct {
 	// +optional
 	// +kubebuilder:default="5m"
 	SyncInterval string `json:"syncInterval,omitempty"`
+
+	// DeletionPolicy controls what happens to the Secret when the TimSecret is deleted
+	// Delete removes it (in any namespace), Retain keeps it and Orphan keeps it
+	// without any operator ownership metadata
+	// +kubebuilder:validation:Enum=Delete;Retain;Orphan
+	// +optional
+	// +kubebuilder:default="Delete"
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// CreationPolicy controls how the operator treats the Secret
+	// Owner creates and owns it, refusing Secrets managed by someone else;
+	// Adopt also takes over existing unmanaged Secrets; Merge writes the Vault
+	// keys into an existing Secret, preserving its other keys; None never writes it
+	// +kubebuilder:validation:Enum=Owner;Merge;None;Adopt
+	// +optional
+	// +kubebuilder:default="Owner"
+	CreationPolicy CreationPolicy `json:"creationPolicy,omitempty"`
+}
+
+// VaultSource is a further Vault path read by a TimSecret
+type VaultSource struct {
+	// VaultPath is the path in Vault where secrets are stored
+	VaultPath string `json:"vaultPath"`
+}
+
+// ConflictPolicy describes how keys found in several sources are resolved
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyError fails the sync when sources disagree on a key
+	ConflictPolicyError ConflictPolicy = "Error"
+
+	// ConflictPolicyFirstWins keeps the value of the earliest source
+	ConflictPolicyFirstWins ConflictPolicy = "FirstWins"
+
+	// ConflictPolicyLastWins keeps the value of the latest source
+	ConflictPolicyLastWins ConflictPolicy = "LastWins"
+)
+
+// SecretDataEntry writes a single Vault key into a Secret key
+type SecretDataEntry struct {
+	// SecretKey is the key in the generated Secret
+	SecretKey string `json:"secretKey"`
+
+	// RemoteKey is the key in the Vault secret
+	RemoteKey string `json:"remoteKey"`
+
+	// Property selects a field of a JSON object stored in RemoteKey,
+	// dot separated for nested fields (e.g. "primary.password")
+	// +optional
+	Property string `json:"property,omitempty"`
+}
+
+// SecretDataFromRule selects Vault keys by regular expressions and transforms
+// their names. Transformations apply in order: rewrite, conversion, prefix and suffix
+type SecretDataFromRule struct {
+	// Include selects the keys matching any of these regular expressions;
+	// all keys if empty
+	// +optional
+	Include []string `json:"include,omitempty"`
+
+	// Exclude drops the keys matching any of these regular expressions
+	// +optional
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Rewrite replaces matches of regular expressions in key names
+	// +optional
+	Rewrite []KeyRewrite `json:"rewrite,omitempty"`
+
+	// Conversion converts the case of key names, e.g. UpperSnake turns
+	// db-password into DB_PASSWORD
+	// +kubebuilder:validation:Enum=Upper;Lower;UpperSnake;LowerSnake
+	// +optional
+	Conversion KeyConversion `json:"conversion,omitempty"`
+
+	// Prefix is prepended to key names
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Suffix is appended to key names
+	// +optional
+	Suffix string `json:"suffix,omitempty"`
+}
+
+// KeyRewrite replaces the matches of a regular expression in key names
+type KeyRewrite struct {
+	// Regexp is the regular expression to match
+	Regexp string `json:"regexp"`
+
+	// Replace is the replacement, $1 and ${name} expand to submatches
+	Replace string `json:"replace"`
+}
+
+// KeyConversion is a case conversion of key names
+type KeyConversion string
+
+const (
+	// KeyConversionUpper upper-cases key names
+	KeyConversionUpper KeyConversion = "Upper"
+
+	// KeyConversionLower lower-cases key names
+	KeyConversionLower KeyConversion = "Lower"
+
+	// KeyConversionUpperSnake converts key names to UPPER_SNAKE_CASE
+	KeyConversionUpperSnake KeyConversion = "UpperSnake"
+
+	// KeyConversionLowerSnake converts key names to lower_snake_case
+	KeyConversionLowerSnake KeyConversion = "LowerSnake"
+)
+
+// CreationPolicy describes how a TimSecret creates and takes ownership of its Secret
+type CreationPolicy string
+
+const (
+	// CreationPolicyOwner creates the Secret and refuses existing Secrets it does not manage
+	CreationPolicyOwner CreationPolicy = "Owner"
+
+	// CreationPolicyAdopt takes over an existing Secret not managed by another TimSecret
+	CreationPolicyAdopt CreationPolicy = "Adopt"
+
+	// CreationPolicyMerge writes the Vault keys into an existing Secret, keeping its other keys
+	CreationPolicyMerge CreationPolicy = "Merge"
+
+	// CreationPolicyNone reads from Vault without creating or updating the Secret
+	CreationPolicyNone CreationPolicy = "None"
+)
+
+// SecretTarget describes the generated Secret
+type SecretTarget struct {
+	// Type is the type of the generated Secret. Typed Secrets must contain the
+	// keys the type requires, e.g. tls.crt and tls.key for kubernetes.io/tls
+	// +kubebuilder:validation:Enum=Opaque;kubernetes.io/tls;kubernetes.io/dockerconfigjson;kubernetes.io/basic-auth;kubernetes.io/ssh-auth
+	// +optional
+	// +kubebuilder:default="Opaque"
+	Type corev1.SecretType `json:"type,omitempty"`
+
+	// KeyMapping maps Secret keys to the Vault keys holding their values,
+	// e.g. tls.crt: certificate. Unmapped Vault keys keep their name.
+	// For kubernetes.io/dockerconfigjson, registry, username, password and email
+	// are assembled into .dockerconfigjson
+	// +optional
+	KeyMapping map[string]string `json:"keyMapping,omitempty"`
+
+	// Template holds metadata and templated data of the generated Secret
+	// +optional
+	Template *SecretTemplate `json:"template,omitempty"`
+
+	// Format renders all keys into a single Secret key, e.g. an app.env or
+	// config.json file
+	// +optional
+	Format *SecretFormat `json:"format,omitempty"`
+
+	// Immutable marks the generated Secret immutable. Changes from Vault
+	// then replace the Secret instead of updating it
+	// +optional
+	Immutable bool `json:"immutable,omitempty"`
+}
+
+// SecretFormat describes a file rendered from all keys of the generated Secret
+type SecretFormat struct {
+	// Type is the file format
+	// +kubebuilder:validation:Enum=dotenv;json;yaml;properties;toml
+	Type SecretFormatType `json:"type"`
+
+	// Key is the Secret key holding the file. Defaults to app.env, config.json,
+	// config.yaml, application.properties or config.toml
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// KeepKeys keeps the individual keys next to the file
+	// +optional
+	KeepKeys bool `json:"keepKeys,omitempty"`
+}
+
+// SecretFormatType is the format of a file rendered into the Secret
+type SecretFormatType string
+
+const (
+	// SecretFormatDotenv renders KEY="value" lines
+	SecretFormatDotenv SecretFormatType = "dotenv"
+
+	// SecretFormatJSON renders a JSON object
+	SecretFormatJSON SecretFormatType = "json"
+
+	// SecretFormatYAML renders a YAML mapping
+	SecretFormatYAML SecretFormatType = "yaml"
+
+	// SecretFormatProperties renders a Java properties file
+	SecretFormatProperties SecretFormatType = "properties"
+
+	// SecretFormatTOML renders a TOML table
+	SecretFormatTOML SecretFormatType = "toml"
+)
+
+// SecretTemplate describes the metadata and data of the generated Secret
+type SecretTemplate struct {
+	// Metadata holds labels and annotations added to the generated Secret
+	// +optional
+	Metadata SecretTemplateMetadata `json:"metadata,omitempty"`
+
+	// Data maps Secret keys to Go templates rendered over the Vault data,
+	// e.g. url: "postgres://{{ .username }}:{{ .password }}@db:5432/app"
+	// +optional
+	Data map[string]string `json:"data,omitempty"`
+
+	// MergePolicy controls which keys end up in the Secret when Data is set
+	// Replace keeps only the templated keys, Merge adds them to the Vault keys
+	// +kubebuilder:validation:Enum=Replace;Merge
+	// +optional
+	// +kubebuilder:default="Replace"
+	MergePolicy TemplateMergePolicy `json:"mergePolicy,omitempty"`
+}
+
+// TemplateMergePolicy describes how templated keys are combined with the Vault keys
+type TemplateMergePolicy string
+
+const (
+	// TemplateMergePolicyReplace keeps only the templated keys
+	TemplateMergePolicyReplace TemplateMergePolicy = "Replace"
+
+	// TemplateMergePolicyMerge adds the templated keys to the Vault keys
+	TemplateMergePolicyMerge TemplateMergePolicy = "Merge"
+)
+
+// SecretTemplateMetadata holds labels and annotations of the generated Secret
+type SecretTemplateMetadata struct {
+	// Labels are added to the generated Secret
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the generated Secret
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// DeletionPolicy describes what happens to a generated Secret when its TimSecret is deleted
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the Secret together with the TimSecret
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+
+	// DeletionPolicyRetain keeps the Secret along with the operator's ownership labels
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+
+	// DeletionPolicyOrphan keeps the Secret, removing the operator's labels and annotations
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+// VaultConfigReference references a TimSecretConfig or ClusterTimSecretConfig
+type VaultConfigReference struct {
+	// Kind is the kind of the referenced configuration
+	// A TimSecretConfig is looked up in the TimSecret's namespace
+	// +kubebuilder:validation:Enum=TimSecretConfig;ClusterTimSecretConfig
+	// +optional
+	// +kubebuilder:default="TimSecretConfig"
+	Kind string `json:"kind,omitempty"`
+
+	// Name is the name of the referenced configuration
+	Name string `json:"name"`
 }
 
 // TimSecretStatus defines the observed state of TimSecret
@@ -66,6 +378,42 @@ type TimSecretStatus struct {
 	// LastError is the last error encountered during sync
 	// +optional
 	LastError string `json:"lastError,omitempty"`
+
+	// DriftCount is the number of times out-of-band changes to the Secret were reverted
+	// +optional
+	DriftCount int `json:"driftCount,omitempty"`
+
+	// LastDriftTime is the last time out-of-band changes to the Secret were reverted
+	// +optional
+	LastDriftTime *metav1.Time `json:"lastDriftTime,omitempty"`
+
+	// Sources records the version read from each Vault path at the last sync,
+	// in source order
+	// +optional
+	Sources []SourceStatus `json:"sources,omitempty"`
+}
+
+// SourceStatus is the version of a Vault path read at the last sync
+type SourceStatus struct {
+	// VaultPath is the path in Vault
+	VaultPath string `json:"vaultPath"`
+
+	// KVVersion is the version of the KV engine, 1 or 2
+	// +optional
+	KVVersion int `json:"kvVersion,omitempty"`
+
+	// Version is the version of the secret read, only set by KV v2
+	// +optional
+	Version int `json:"version,omitempty"`
+
+	// CurrentVersion is the latest version of the secret, only set by KV v2.
+	// It differs from Version when an older version is pinned
+	// +optional
+	CurrentVersion int `json:"currentVersion,omitempty"`
+
+	// CustomMetadata is the custom metadata of the secret, only set by KV v2
+	// +optional
+	CustomMetadata map[string]string `json:"customMetadata,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -10,10 +10,264 @@ type TimSecretConfigSpec struct {
 	VaultURL string `json:"vaultURL"`
 
 	// VaultToken is the authentication token for Vault
-	VaultToken string `json:"vaultToken"`
+	// Deprecated: use TokenSecretRef instead. Ignored when Auth or TokenSecretRef is specified
+	// +optional
+	VaultToken string `json:"vaultToken,omitempty"`
+
+	// TokenSecretRef references the Secret key holding the Vault token
+	// Ignored when Auth is specified
+	// +optional
+	TokenSecretRef *SecretKeySelector `json:"tokenSecretRef,omitempty"`
+
+	// VaultNamespace is the Vault Enterprise namespace used for login and reads
+	// (X-Vault-Namespace). Can be overridden per TimSecret
+	// +optional
+	VaultNamespace string `json:"vaultNamespace,omitempty"`
+
+	// Auth configures the Vault auth method used to obtain a token
+	// +optional
+	Auth *VaultAuth `json:"auth,omitempty"`
+
+	// TLS configures the TLS connection to Vault
+	// +optional
+	TLS *VaultTLS `json:"tls,omitempty"`
+
+	// AllowedPaths restricts the Vault paths TimSecrets may read through this config
+	// A path is allowed when it matches a rule applying to the TimSecret's namespace
+	// If not specified, every path the token can read is allowed
+	// +optional
+	AllowedPaths []AllowedPathRule `json:"allowedPaths,omitempty"`
+}
+
+// AllowedPathRule allows a set of Vault paths for a set of namespaces
+type AllowedPathRule struct {
+	// Namespaces the rule applies to
+	// If not specified, the rule applies to every namespace
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Paths are the allowed Vault paths, using Vault policy syntax: "+" matches
+	// a single path segment and a trailing "*" matches any suffix, e.g.
+	// "secret/data/{{ .Namespace }}/*". {{ .Namespace }} is the TimSecret's namespace
+	Paths []string `json:"paths"`
+}
+
+// VaultTLS configures how the operator verifies and authenticates to the Vault server
+type VaultTLS struct {
+	// CA references PEM encoded CA certificates used to verify the Vault server
+	// If not specified, the system CA pool is used
+	// +optional
+	CA *CABundleRef `json:"ca,omitempty"`
+
+	// ClientCertSecretRef references a kubernetes.io/tls Secret in the
+	// TimSecretConfig's namespace whose tls.crt and tls.key are presented to Vault (mTLS)
+	// +optional
+	ClientCertSecretRef *LocalSecretRef `json:"clientCertSecretRef,omitempty"`
+
+	// ServerName is the server name used for SNI and certificate verification
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
+
+	// InsecureSkipVerify disables verification of the Vault server certificate
+	// Only use this for development
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// CABundleRef references a key of a ConfigMap or Secret holding CA certificates
+type CABundleRef struct {
+	// Kind is the kind of the referenced object
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +optional
+	// +kubebuilder:default="ConfigMap"
+	Kind string `json:"kind,omitempty"`
+
+	// Name is the name of the ConfigMap or Secret in the TimSecretConfig's namespace
+	Name string `json:"name"`
+
+	// Key is the key holding the PEM encoded certificates
+	// Default is "ca.crt"
+	// +optional
+	// +kubebuilder:default="ca.crt"
+	Key string `json:"key,omitempty"`
+}
+
+// LocalSecretRef references a Secret in the same namespace
+type LocalSecretRef struct {
+	// Name is the name of the Secret
+	Name string `json:"name"`
+}
+
+// VaultAuth selects the Vault auth method used by the operator
+type VaultAuth struct {
+	// Kubernetes logs in with a ServiceAccount token using Vault's kubernetes auth method
+	// +optional
+	Kubernetes *KubernetesAuth `json:"kubernetes,omitempty"`
+
+	// AppRole logs in with a role_id and secret_id using Vault's approle auth method
+	// +optional
+	AppRole *AppRoleAuth `json:"appRole,omitempty"`
+
+	// Cert logs in with a TLS client certificate using Vault's cert auth method
+	// +optional
+	Cert *CertAuth `json:"cert,omitempty"`
+
+	// JWT logs in with a projected ServiceAccount token using Vault's jwt auth method
+	// +optional
+	JWT *JWTAuth `json:"jwt,omitempty"`
+
+	// RoleTemplate derives the Vault role from the namespace of the TimSecret
+	// being synced, e.g. "ns-{{ .Namespace }}". It replaces the role of the
+	// kubernetes and jwt methods and the name of the cert method, and every
+	// TimSecret namespace gets its own login and token so that Vault policies
+	// decide which namespace can read which path. Not supported with AppRole
+	// +optional
+	RoleTemplate string `json:"roleTemplate,omitempty"`
+}
+
+// KubernetesAuth configures Vault's kubernetes auth method
+type KubernetesAuth struct {
+	// MountPath is the path where the kubernetes auth method is mounted
+	// Default is "kubernetes"
+	// +optional
+	// +kubebuilder:default="kubernetes"
+	MountPath string `json:"mountPath,omitempty"`
+
+	// Role is the Vault role to log in with
+	// Optional when auth.roleTemplate is set
+	// +optional
+	Role string `json:"role,omitempty"`
+
+	// Audience is the audience of the requested ServiceAccount token
+	// Defaults to "vault". Requires ServiceAccountName. On TimSecretConfigs it
+	// must be allowed by the operator's --service-account-token-audiences flag
+	// +optional
+	Audience string `json:"audience,omitempty"`
+
+	// ServiceAccountName is the ServiceAccount in the TimSecretConfig's namespace
+	// whose token is used to log in. Required on TimSecretConfigs, where the
+	// ServiceAccount must be annotated with secrets.tim.operator/allow-vault-auth=true;
+	// a ClusterTimSecretConfig without it uses the operator's own ServiceAccount token
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// JWTAuth configures Vault's jwt auth method with a ServiceAccount token as JWT
+type JWTAuth struct {
+	// MountPath is the path where the jwt auth method is mounted
+	// Default is "jwt"
+	// +optional
+	// +kubebuilder:default="jwt"
+	MountPath string `json:"mountPath,omitempty"`
+
+	// Role is the Vault role to log in with
+	// Optional when auth.roleTemplate is set
+	// +optional
+	Role string `json:"role,omitempty"`
+
+	// Audience is the audience of the requested ServiceAccount token
+	// It must match the bound_audiences of the Vault role. Defaults to "vault".
+	// Requires ServiceAccountName. On TimSecretConfigs it must be allowed by
+	// the operator's --service-account-token-audiences flag
+	// +optional
+	Audience string `json:"audience,omitempty"`
+
+	// ServiceAccountName is the ServiceAccount in the TimSecretConfig's namespace
+	// whose token is used to log in. Required on TimSecretConfigs, where the
+	// ServiceAccount must be annotated with secrets.tim.operator/allow-vault-auth=true;
+	// a ClusterTimSecretConfig without it uses the operator's own ServiceAccount token
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// AppRoleAuth configures Vault's approle auth method
+type AppRoleAuth struct {
+	// MountPath is the path where the approle auth method is mounted
+	// Default is "approle"
+	// +optional
+	// +kubebuilder:default="approle"
+	MountPath string `json:"mountPath,omitempty"`
+
+	// SecretRef references the Secret in the TimSecretConfig's namespace
+	// holding the role_id and secret_id
+	SecretRef AppRoleSecretRef `json:"secretRef"`
+}
+
+// AppRoleSecretRef references a Secret holding AppRole credentials
+type AppRoleSecretRef struct {
+	// Name is the name of the Secret
+	Name string `json:"name"`
+
+	// RoleIDKey is the key holding the role_id
+	// Default is "role_id"
+	// +optional
+	// +kubebuilder:default="role_id"
+	RoleIDKey string `json:"roleIDKey,omitempty"`
+
+	// SecretIDKey is the key holding the secret_id
+	// Default is "secret_id"
+	// +optional
+	// +kubebuilder:default="secret_id"
+	SecretIDKey string `json:"secretIDKey,omitempty"`
+}
+
+// CertAuth configures Vault's cert auth method
+type CertAuth struct {
+	// MountPath is the path where the cert auth method is mounted
+	// Default is "cert"
+	// +optional
+	// +kubebuilder:default="cert"
+	MountPath string `json:"mountPath,omitempty"`
+
+	// Name is the certificate role to log in with
+	// If not specified, Vault tries every role matching the certificate
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// SecretRef references a kubernetes.io/tls Secret in the TimSecretConfig's
+	// namespace holding the client certificate and key. The certificate is also
+	// presented on every TLS connection to Vault.
+	SecretRef LocalSecretRef `json:"secretRef"`
+}
+
+// TimSecretConfigStatus defines the observed state of the Vault token
+type TimSecretConfigStatus struct {
+	// TokenTTL is the remaining time to live of the Vault token when it was last checked
+	// Empty if the token never expires
+	// +optional
+	TokenTTL string `json:"tokenTTL,omitempty"`
+
+	// TokenExpireTime is when the Vault token expires
+	// +optional
+	TokenExpireTime *metav1.Time `json:"tokenExpireTime,omitempty"`
+
+	// TokenRenewable indicates whether the Vault token can be renewed
+	// +optional
+	TokenRenewable bool `json:"tokenRenewable,omitempty"`
+
+	// LastCheckTime is the last time the Vault token was looked up
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+
+	// LastRenewalTime is the last time the Vault token was renewed or re-authenticated
+	// +optional
+	LastRenewalTime *metav1.Time `json:"lastRenewalTime,omitempty"`
+
+	// RenewalFailures is the number of consecutive failed token checks
+	// +optional
+	RenewalFailures int `json:"renewalFailures,omitempty"`
+
+	// LastError is the last error encountered while managing the token
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// Conditions represent the latest available observations of the token's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Namespaced
 
 // TimSecretConfig is the Schema for centralized Vault configuration
@@ -21,7 +275,8 @@ type TimSecretConfig struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec TimSecretConfigSpec `json:"spec,omitempty"`
+	Spec   TimSecretConfigSpec   `json:"spec,omitempty"`
+	Status TimSecretConfigStatus `json:"status,omitempty"`
 }
 
 // +kubebuilder:object:root=true
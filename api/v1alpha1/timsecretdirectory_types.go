@@ -0,0 +1,123 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TimSecretDirectorySpec defines the desired state of TimSecretDirectory
+type TimSecretDirectorySpec struct {
+	// VaultConfigRef references the TimSecretConfig or ClusterTimSecretConfig to use
+	VaultConfigRef *VaultConfigReference `json:"vaultConfigRef"`
+
+	// VaultNamespace is the Vault Enterprise namespace used for login, lists and reads
+	// Overrides the VaultNamespace of the TimSecretConfig
+	// +optional
+	VaultNamespace string `json:"vaultNamespace,omitempty"`
+
+	// VaultPath is the path prefix in Vault listed recursively for secrets
+	VaultPath string `json:"vaultPath"`
+
+	// Mode controls how the secrets below VaultPath become Secrets
+	// SecretPerLeaf creates one Secret per secret, Merge merges all into one Secret
+	// +kubebuilder:validation:Enum=SecretPerLeaf;Merge
+	// +optional
+	// +kubebuilder:default="SecretPerLeaf"
+	Mode DirectoryMode `json:"mode,omitempty"`
+
+	// SecretName names the generated Secrets. With SecretPerLeaf it is a Go
+	// template rendered for every secret with .Directory, .Path (relative to
+	// VaultPath) and .Name (last path segment); with Merge it is the name of
+	// the single Secret
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// MaxSecrets is the maximum number of secrets below VaultPath. Syncing
+	// stops if there are more, so a mistyped prefix cannot create thousands of Secrets
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	// +kubebuilder:default=100
+	MaxSecrets int `json:"maxSecrets,omitempty"`
+
+	// ConflictPolicy resolves keys found in several secrets with Merge
+	// +kubebuilder:validation:Enum=Error;FirstWins;LastWins
+	// +optional
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+
+	// Target describes the type and keys of the generated Secrets
+	// +optional
+	Target *SecretTarget `json:"target,omitempty"`
+
+	// SyncInterval is the interval between listings of VaultPath and syncs
+	// of the generated Secrets
+	// +optional
+	// +kubebuilder:default="5m"
+	SyncInterval string `json:"syncInterval,omitempty"`
+
+	// DeletionPolicy controls what happens to the generated Secrets when
+	// their secret disappears from Vault or the TimSecretDirectory is deleted
+	// +kubebuilder:validation:Enum=Delete;Retain;Orphan
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// CreationPolicy controls how the generated TimSecrets treat existing Secrets
+	// +kubebuilder:validation:Enum=Owner;Merge;None;Adopt
+	// +optional
+	CreationPolicy CreationPolicy `json:"creationPolicy,omitempty"`
+}
+
+// DirectoryMode describes how a TimSecretDirectory turns secrets into Secrets
+type DirectoryMode string
+
+const (
+	// DirectoryModeSecretPerLeaf creates one Secret per secret below the path
+	DirectoryModeSecretPerLeaf DirectoryMode = "SecretPerLeaf"
+
+	// DirectoryModeMerge merges all secrets below the path into one Secret
+	DirectoryModeMerge DirectoryMode = "Merge"
+)
+
+// TimSecretDirectoryStatus defines the observed state of TimSecretDirectory
+type TimSecretDirectoryStatus struct {
+	// LastSyncTime is the last time VaultPath was listed
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// SecretCount is the number of secrets found below VaultPath
+	// +optional
+	SecretCount int `json:"secretCount,omitempty"`
+
+	// Conditions represent the latest available observations of the TimSecretDirectory's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// LastError is the last error encountered during sync
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Namespaced
+
+// TimSecretDirectory generates a TimSecret for every secret below a Vault
+// path prefix, or one TimSecret merging all of them
+type TimSecretDirectory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TimSecretDirectorySpec   `json:"spec,omitempty"`
+	Status TimSecretDirectoryStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TimSecretDirectoryList contains a list of TimSecretDirectory
+type TimSecretDirectoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TimSecretDirectory `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TimSecretDirectory{}, &TimSecretDirectoryList{})
+}
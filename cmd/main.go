@@ -1,18 +1,26 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+	"github.com/renatoruis/timvault-operator/internal/certs"
 	"github.com/renatoruis/timvault-operator/internal/controller"
+	"github.com/renatoruis/timvault-operator/internal/vault"
 )
 
 var (
@@ -29,12 +37,52 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var rejectInlineTokens bool
+	var rejectCrossNamespaceConfig bool
+	var serviceAccountTokenAudiences string
+	var clusterResourceNamespace string
+	var allowCrossNamespaceTargets bool
+	var enableWebhooks bool
+	var webhookPort int
+	var webhookCertDir string
+	var manageWebhookCerts bool
+	var webhookServiceName string
+	var webhookServiceNamespace string
+	var webhookCertSecret string
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&rejectInlineTokens, "reject-inline-vault-tokens", false,
+		"Reject TimSecrets and TimSecretConfigs using the deprecated inline vaultToken field. "+
+			"Tokens must then be provided through tokenSecretRef.")
+	flag.BoolVar(&rejectCrossNamespaceConfig, "reject-cross-namespace-config", false,
+		"Reject TimSecrets using vaultConfigNamespace to reference a TimSecretConfig in another namespace, even when "+
+			"that namespace allows it through the "+controller.AllowConfigFromAnnotation+" annotation. "+
+			"Shared configurations must then be ClusterTimSecretConfigs.")
+	flag.StringVar(&serviceAccountTokenAudiences, "service-account-token-audiences", "vault",
+		"Comma-separated ServiceAccount token audiences TimSecretConfigs may request for Vault kubernetes and jwt auth. "+
+			"Never include an audience the kube-apiserver accepts.")
+	flag.StringVar(&clusterResourceNamespace, "cluster-resource-namespace", "timvault-operator-system",
+		"The namespace holding the Secrets, ConfigMaps and ServiceAccounts referenced by ClusterTimSecretConfigs.")
+	flag.BoolVar(&allowCrossNamespaceTargets, "allow-cross-namespace-targets", false,
+		"Allow TimSecrets to write Secrets and restart Deployments in other namespaces without the target namespace "+
+			"opting in through the "+controller.AllowTargetsFromAnnotation+" annotation.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false, "Serve the validating and defaulting admission webhooks.")
+	flag.IntVar(&webhookPort, "webhook-port", 9443, "The port the webhook server listens on.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs"),
+		"The directory holding tls.crt and tls.key for the webhook server.")
+	flag.BoolVar(&manageWebhookCerts, "manage-webhook-certs", true,
+		"Generate and rotate the webhook server certificate and inject its CA into the webhook configurations. "+
+			"Disable to provide the certificate in --webhook-cert-dir yourself.")
+	flag.StringVar(&webhookServiceName, "webhook-service-name", "timvault-operator-webhook",
+		"The name of the Service fronting the webhook server.")
+	flag.StringVar(&webhookServiceNamespace, "webhook-service-namespace", "timvault-operator-system",
+		"The namespace of the webhook Service and of the Secret holding the webhook certificates.")
+	flag.StringVar(&webhookCertSecret, "webhook-cert-secret", "timvault-operator-webhook-certs",
+		"The name of the Secret holding the generated webhook certificates.")
 
 	opts := zap.Options{
 		Development: true,
@@ -44,25 +92,116 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	tokenAudiences := splitList(serviceAccountTokenAudiences)
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "timvault-operator.tim.operator",
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Port:    webhookPort,
+			CertDir: webhookCertDir,
+		}),
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	// Shared by all controllers so tokens renewed by the config controllers
+	// are the ones used to read secrets
+	vaultClients := vault.NewClientCache()
+
 	if err = (&controller.TimSecretReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:                       mgr.GetClient(),
+		Scheme:                       mgr.GetScheme(),
+		VaultClients:                 vaultClients,
+		RejectInlineTokens:           rejectInlineTokens,
+		ServiceAccountTokenAudiences: tokenAudiences,
+		RejectCrossNamespaceConfig:   rejectCrossNamespaceConfig,
+		ClusterResourceNamespace:     clusterResourceNamespace,
+		AllowCrossNamespaceTargets:   allowCrossNamespaceTargets,
+		Recorder:                     mgr.GetEventRecorderFor("timsecret-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "TimSecret")
 		os.Exit(1)
 	}
 
+	if err = (&controller.TimSecretConfigReconciler{
+		Client:                       mgr.GetClient(),
+		Scheme:                       mgr.GetScheme(),
+		VaultClients:                 vaultClients,
+		RejectInlineTokens:           rejectInlineTokens,
+		ServiceAccountTokenAudiences: tokenAudiences,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TimSecretConfig")
+		os.Exit(1)
+	}
+
+	if err = (&controller.ClusterTimSecretConfigReconciler{
+		Client:                   mgr.GetClient(),
+		Scheme:                   mgr.GetScheme(),
+		VaultClients:             vaultClients,
+		RejectInlineTokens:       rejectInlineTokens,
+		ClusterResourceNamespace: clusterResourceNamespace,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterTimSecretConfig")
+		os.Exit(1)
+	}
+
+	if err = (&controller.TimSecretDirectoryReconciler{
+		Client:                       mgr.GetClient(),
+		Scheme:                       mgr.GetScheme(),
+		VaultClients:                 vaultClients,
+		RejectInlineTokens:           rejectInlineTokens,
+		ServiceAccountTokenAudiences: tokenAudiences,
+		RejectCrossNamespaceConfig:   rejectCrossNamespaceConfig,
+		ClusterResourceNamespace:     clusterResourceNamespace,
+		AllowCrossNamespaceTargets:   allowCrossNamespaceTargets,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TimSecretDirectory")
+		os.Exit(1)
+	}
+
+	if enableWebhooks {
+		if manageWebhookCerts {
+			if err := setupWebhookCerts(mgr, webhookCertDir, webhookServiceName, webhookServiceNamespace, webhookCertSecret); err != nil {
+				setupLog.Error(err, "unable to provision webhook certificates")
+				os.Exit(1)
+			}
+		}
+
+		if err = (&controller.TimSecretWebhook{
+			Client:                     mgr.GetClient(),
+			AllowCrossNamespaceTargets: allowCrossNamespaceTargets,
+			RejectInlineTokens:         rejectInlineTokens,
+			RejectCrossNamespaceConfig: rejectCrossNamespaceConfig,
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "TimSecret")
+			os.Exit(1)
+		}
+
+		if err = (&controller.TimSecretConfigWebhook{
+			RejectInlineTokens:           rejectInlineTokens,
+			ServiceAccountTokenAudiences: tokenAudiences,
+			ClusterResourceNamespace:     clusterResourceNamespace,
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "TimSecretConfig")
+			os.Exit(1)
+		}
+
+		if err = (&controller.TimSecretDirectoryWebhook{
+			Client:                     mgr.GetClient(),
+			AllowCrossNamespaceTargets: allowCrossNamespaceTargets,
+			RejectInlineTokens:         rejectInlineTokens,
+			RejectCrossNamespaceConfig: rejectCrossNamespaceConfig,
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "TimSecretDirectory")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -78,3 +217,43 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// setupWebhookCerts provisions the webhook server certificate before the
+// manager starts, as the webhook server needs it on startup, and registers
+// the provisioner to rotate it afterwards
+func setupWebhookCerts(mgr ctrl.Manager, certDir, serviceName, serviceNamespace, secretName string) error {
+	// The manager's cache is not running yet
+	c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return err
+	}
+
+	provisioner := &certs.Provisioner{
+		Client:                         c,
+		Namespace:                      serviceNamespace,
+		SecretName:                     secretName,
+		ServiceName:                    serviceName,
+		CertDir:                        certDir,
+		ValidatingWebhookConfiguration: "timvault-operator-validating-webhook",
+		MutatingWebhookConfiguration:   "timvault-operator-mutating-webhook",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := provisioner.Provision(ctx); err != nil {
+		return err
+	}
+
+	return mgr.Add(provisioner)
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
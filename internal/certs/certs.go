@@ -0,0 +1,398 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// caValidity and certValidity are the lifetimes of generated certificates
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 365 * 24 * time.Hour
+
+	// rotationThreshold is how long before expiry a certificate is replaced
+	rotationThreshold = 30 * 24 * time.Hour
+
+	// checkInterval is how often the certificates are checked
+	checkInterval = 12 * time.Hour
+
+	// Keys of the Secret holding the certificates
+	caCertKey = "ca.crt"
+	caKeyKey  = "ca.key"
+)
+
+// Provisioner manages the webhook server certificate without cert-manager:
+// it keeps a self-signed CA and a serving certificate in a Secret, writes
+// the serving certificate to the webhook server's directory and injects the
+// CA into the webhook configurations. It implements manager.Runnable to
+// rotate the certificate before it expires.
+type Provisioner struct {
+	// Client must not be backed by the manager's cache, as the certificates
+	// are provisioned before the manager starts
+	Client client.Client
+
+	// Namespace and SecretName identify the Secret holding the certificates
+	Namespace  string
+	SecretName string
+
+	// ServiceName is the Service in Namespace fronting the webhook server
+	ServiceName string
+
+	// CertDir is where tls.crt and tls.key are written for the webhook server
+	CertDir string
+
+	// ValidatingWebhookConfiguration and MutatingWebhookConfiguration are
+	// the names of the webhook configurations whose caBundle is managed
+	ValidatingWebhookConfiguration string
+	MutatingWebhookConfiguration   string
+}
+
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations;mutatingwebhookconfigurations,verbs=get;update
+
+// Start re-provisions the certificates periodically until the context is done
+func (p *Provisioner) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("webhook-certs")
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := p.Provision(ctx); err != nil {
+				logger.Error(err, "Failed to provision webhook certificates")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable: every replica
+// serves webhooks and needs the certificate on disk
+func (p *Provisioner) NeedLeaderElection() bool {
+	return false
+}
+
+// Provision makes sure a valid certificate is stored, written to disk and trusted by the webhook configurations
+func (p *Provisioner) Provision(ctx context.Context) error {
+	secret, err := p.ensureSecret(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := p.writeCertDir(secret); err != nil {
+		return err
+	}
+
+	return p.injectCABundle(ctx, secret.Data[caCertKey])
+}
+
+// dnsNames returns the names the webhook server is reached by
+func (p *Provisioner) dnsNames() []string {
+	return []string{
+		p.ServiceName,
+		fmt.Sprintf("%s.%s", p.ServiceName, p.Namespace),
+		fmt.Sprintf("%s.%s.svc", p.ServiceName, p.Namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", p.ServiceName, p.Namespace),
+	}
+}
+
+// ensureSecret returns the Secret holding valid certificates, creating or
+// renewing them when needed
+func (p *Provisioner) ensureSecret(ctx context.Context) (*corev1.Secret, error) {
+	logger := log.FromContext(ctx)
+
+	secret := &corev1.Secret{}
+	err := p.Client.Get(ctx, types.NamespacedName{Name: p.SecretName, Namespace: p.Namespace}, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get Secret %s/%s: %w", p.Namespace, p.SecretName, err)
+	}
+	exists := err == nil
+
+	now := time.Now()
+	if exists && validCertificates(secret.Data, p.dnsNames(), now) {
+		return secret, nil
+	}
+
+	data, err := p.renew(secret.Data, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: p.SecretName, Namespace: p.Namespace},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       data,
+		}
+		if err := p.Client.Create(ctx, secret); err != nil {
+			if errors.IsAlreadyExists(err) {
+				// Another replica created it first
+				return p.ensureSecret(ctx)
+			}
+			return nil, fmt.Errorf("failed to create Secret %s/%s: %w", p.Namespace, p.SecretName, err)
+		}
+		logger.Info("Generated webhook certificates", "secret", p.SecretName)
+		return secret, nil
+	}
+
+	secret.Data = data
+	if err := p.Client.Update(ctx, secret); err != nil {
+		if errors.IsConflict(err) {
+			// Another replica renewed it first
+			return p.ensureSecret(ctx)
+		}
+		return nil, fmt.Errorf("failed to update Secret %s/%s: %w", p.Namespace, p.SecretName, err)
+	}
+	logger.Info("Renewed webhook certificates", "secret", p.SecretName)
+	return secret, nil
+}
+
+// renew issues a new serving certificate, keeping the CA while it stays
+// valid so the caBundle of the webhook configurations does not change
+func (p *Provisioner) renew(existing map[string][]byte, now time.Time) (map[string][]byte, error) {
+	caCertPEM, caKeyPEM := existing[caCertKey], existing[caKeyKey]
+	caCert, caKey, err := parseKeyPair(caCertPEM, caKeyPEM)
+	if err != nil || now.Add(certValidity+rotationThreshold).After(caCert.NotAfter) {
+		caCertPEM, caKeyPEM, err = generateCA(now)
+		if err != nil {
+			return nil, err
+		}
+		if caCert, caKey, err = parseKeyPair(caCertPEM, caKeyPEM); err != nil {
+			return nil, err
+		}
+	}
+
+	certPEM, keyPEM, err := generateServingCert(caCert, caKey, p.dnsNames(), now)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string][]byte{
+		caCertKey:               caCertPEM,
+		caKeyKey:                caKeyPEM,
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+	}, nil
+}
+
+// writeCertDir writes the serving certificate for the webhook server, which
+// reloads it when the files change
+func (p *Provisioner) writeCertDir(secret *corev1.Secret) error {
+	if err := os.MkdirAll(p.CertDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create webhook certificate directory: %w", err)
+	}
+
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		path := filepath.Join(p.CertDir, key)
+		if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, secret.Data[key]) {
+			continue
+		}
+		if err := os.WriteFile(path, secret.Data[key], 0o600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// injectCABundle sets the caBundle of every webhook in the webhook configurations
+func (p *Provisioner) injectCABundle(ctx context.Context, caBundle []byte) error {
+	if p.ValidatingWebhookConfiguration != "" {
+		config := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		clientConfigs := func() []*admissionregistrationv1.WebhookClientConfig {
+			configs := make([]*admissionregistrationv1.WebhookClientConfig, len(config.Webhooks))
+			for i := range config.Webhooks {
+				configs[i] = &config.Webhooks[i].ClientConfig
+			}
+			return configs
+		}
+		if err := p.updateCABundle(ctx, p.ValidatingWebhookConfiguration, config, clientConfigs, caBundle); err != nil {
+			return err
+		}
+	}
+
+	if p.MutatingWebhookConfiguration != "" {
+		config := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		clientConfigs := func() []*admissionregistrationv1.WebhookClientConfig {
+			configs := make([]*admissionregistrationv1.WebhookClientConfig, len(config.Webhooks))
+			for i := range config.Webhooks {
+				configs[i] = &config.Webhooks[i].ClientConfig
+			}
+			return configs
+		}
+		if err := p.updateCABundle(ctx, p.MutatingWebhookConfiguration, config, clientConfigs, caBundle); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateCABundle fetches a webhook configuration and updates the caBundle of
+// the client configs returned by clientConfigs if it changed. A missing
+// configuration is skipped, as the webhooks may not be installed.
+func (p *Provisioner) updateCABundle(ctx context.Context, name string, config client.Object, clientConfigs func() []*admissionregistrationv1.WebhookClientConfig, caBundle []byte) error {
+	if err := p.Client.Get(ctx, types.NamespacedName{Name: name}, config); err != nil {
+		if errors.IsNotFound(err) {
+			log.FromContext(ctx).Info("Webhook configuration not found, skipping caBundle injection", "name", name)
+			return nil
+		}
+		return fmt.Errorf("failed to get webhook configuration %s: %w", name, err)
+	}
+
+	changed := false
+	for _, clientConfig := range clientConfigs() {
+		if !bytes.Equal(clientConfig.CABundle, caBundle) {
+			clientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	if err := p.Client.Update(ctx, config); err != nil {
+		return fmt.Errorf("failed to update webhook configuration %s: %w", name, err)
+	}
+	return nil
+}
+
+// validCertificates reports whether the Secret data holds a CA and a serving
+// certificate signed by it, valid for the DNS names and not about to expire
+func validCertificates(data map[string][]byte, dnsNames []string, now time.Time) bool {
+	caCert, _, err := parseKeyPair(data[caCertKey], data[caKeyKey])
+	if err != nil {
+		return false
+	}
+	cert, _, err := parseKeyPair(data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return false
+	}
+
+	if now.Add(rotationThreshold).After(cert.NotAfter) {
+		return false
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	for _, name := range dnsNames {
+		if _, err := cert.Verify(x509.VerifyOptions{DNSName: name, Roots: roots, CurrentTime: now}); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// generateCA creates a self-signed CA certificate and key
+func generateCA(now time.Time) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber(),
+		Subject:               pkix.Name{CommonName: "timvault-operator-webhook-ca"},
+		NotBefore:             now.Add(-1 * time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+
+	return encodeKeyPair(der, key)
+}
+
+// generateServingCert creates a serving certificate for the DNS names signed by the CA
+func generateServingCert(caCert *x509.Certificate, caKey *ecdsa.PrivateKey, dnsNames []string, now time.Time) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate serving key: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber(),
+		Subject:      pkix.Name{CommonName: dnsNames[len(dnsNames)-2]},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-1 * time.Hour),
+		NotAfter:     now.Add(certValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create serving certificate: %w", err)
+	}
+
+	return encodeKeyPair(der, key)
+}
+
+// encodeKeyPair PEM encodes a DER certificate and its key
+func encodeKeyPair(der []byte, key *ecdsa.PrivateKey) ([]byte, []byte, error) {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode key: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// parseKeyPair decodes a PEM certificate and EC key generated by this package
+func parseKeyPair(certPEM, keyPEM []byte) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, nil, fmt.Errorf("no certificate found")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, nil, fmt.Errorf("no key found")
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cert, key, nil
+}
+
+// serialNumber returns a random certificate serial number
+func serialNumber() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return big.NewInt(time.Now().UnixNano())
+	}
+	return serial
+}
@@ -0,0 +1,178 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRenew(t *testing.T) {
+	p := &Provisioner{Namespace: "timvault-operator-system", ServiceName: "timvault-operator-webhook"}
+	now := time.Now()
+
+	data, err := p.renew(nil, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !validCertificates(data, p.dnsNames(), now) {
+		t.Fatal("expected new certificates to be valid")
+	}
+
+	cert, _, err := parseKeyPair(data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"timvault-operator-webhook",
+		"timvault-operator-webhook.timvault-operator-system",
+		"timvault-operator-webhook.timvault-operator-system.svc",
+		"timvault-operator-webhook.timvault-operator-system.svc.cluster.local",
+	}
+	if !reflect.DeepEqual(cert.DNSNames, want) {
+		t.Errorf("got SANs %v, want %v", cert.DNSNames, want)
+	}
+
+	// Another Service name is not covered
+	if validCertificates(data, []string{"other.timvault-operator-system.svc"}, now) {
+		t.Error("expected certificates to be invalid for another name")
+	}
+
+	// The serving certificate is replaced within the rotation threshold
+	expiring := cert.NotAfter.Add(-rotationThreshold + time.Hour)
+	if validCertificates(data, p.dnsNames(), expiring) {
+		t.Error("expected certificates about to expire to be invalid")
+	}
+
+	// Renewal keeps a CA that outlives the new serving certificate
+	renewed, err := p.renew(data, expiring)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(renewed[caCertKey], data[caCertKey]) || bytes.Equal(renewed[corev1.TLSCertKey], data[corev1.TLSCertKey]) {
+		t.Error("expected a new serving certificate signed by the same CA")
+	}
+	if !validCertificates(renewed, p.dnsNames(), expiring) {
+		t.Error("expected renewed certificates to be valid")
+	}
+
+	// A CA expiring before the new serving certificate is replaced
+	caCert, _, _ := parseKeyPair(data[caCertKey], data[caKeyKey])
+	late := caCert.NotAfter.Add(-certValidity)
+	renewed, err = p.renew(data, late)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Equal(renewed[caCertKey], data[caCertKey]) {
+		t.Error("expected a new CA")
+	}
+	if !validCertificates(renewed, p.dnsNames(), late) {
+		t.Error("expected certificates with a new CA to be valid")
+	}
+}
+
+func TestProvision(t *testing.T) {
+	ctx := context.Background()
+	webhooks := func(names ...string) []admissionregistrationv1.ValidatingWebhook {
+		var hooks []admissionregistrationv1.ValidatingWebhook
+		for _, name := range names {
+			hooks = append(hooks, admissionregistrationv1.ValidatingWebhook{Name: name})
+		}
+		return hooks
+	}
+	validating := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "validating"},
+		Webhooks:   webhooks("vtimsecret.secrets.tim.operator", "vtimsecretconfig.secrets.tim.operator"),
+	}
+	mutating := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "mutating"},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{Name: "mtimsecret.secrets.tim.operator"},
+			{Name: "mtimsecretconfig.secrets.tim.operator"},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(validating, mutating).Build()
+
+	p := &Provisioner{
+		Client:                         c,
+		Namespace:                      "timvault-operator-system",
+		SecretName:                     "webhook-certs",
+		ServiceName:                    "timvault-operator-webhook",
+		CertDir:                        filepath.Join(t.TempDir(), "certs"),
+		ValidatingWebhookConfiguration: "validating",
+		MutatingWebhookConfiguration:   "mutating",
+	}
+	if err := p.Provision(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "webhook-certs", Namespace: "timvault-operator-system"}, secret); err != nil {
+		t.Fatalf("expected the Secret to be created: %v", err)
+	}
+	caBundle := secret.Data[caCertKey]
+
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		written, err := os.ReadFile(filepath.Join(p.CertDir, key))
+		if err != nil || !bytes.Equal(written, secret.Data[key]) {
+			t.Errorf("expected %s to be written to the certificate directory", key)
+		}
+	}
+
+	if err := c.Get(ctx, types.NamespacedName{Name: "validating"}, validating); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "mutating"}, mutating); err != nil {
+		t.Fatal(err)
+	}
+	for _, hook := range validating.Webhooks {
+		if !bytes.Equal(hook.ClientConfig.CABundle, caBundle) {
+			t.Errorf("%s: caBundle not injected", hook.Name)
+		}
+	}
+	for _, hook := range mutating.Webhooks {
+		if !bytes.Equal(hook.ClientConfig.CABundle, caBundle) {
+			t.Errorf("%s: caBundle not injected", hook.Name)
+		}
+	}
+
+	// Valid certificates are left alone
+	version := secret.ResourceVersion
+	if err := p.Provision(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "webhook-certs", Namespace: "timvault-operator-system"}, secret); err != nil {
+		t.Fatal(err)
+	}
+	if secret.ResourceVersion != version {
+		t.Error("expected valid certificates not to be renewed")
+	}
+
+	// Certificates for another Service are replaced, keeping the CA
+	p.ServiceName = "timvault-operator-webhook-v2"
+	if err := p.Provision(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: "webhook-certs", Namespace: "timvault-operator-system"}, secret); err != nil {
+		t.Fatal(err)
+	}
+	if !validCertificates(secret.Data, p.dnsNames(), time.Now()) || !bytes.Equal(secret.Data[caCertKey], caBundle) {
+		t.Error("expected the serving certificate to be renewed for the new Service with the same CA")
+	}
+
+	// Missing webhook configurations are skipped
+	p.ValidatingWebhookConfiguration = "missing"
+	if err := p.Provision(ctx); err != nil {
+		t.Errorf("unexpected error for a missing configuration: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+	"github.com/renatoruis/timvault-operator/internal/vault"
+)
+
+// ClusterTimSecretConfigReconciler manages the Vault token of each
+// ClusterTimSecretConfig, like the TimSecretConfigReconciler does for
+// namespaced configurations
+type ClusterTimSecretConfigReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// VaultClients caches authenticated Vault clients per configuration.
+	// Must be shared with the TimSecretReconciler so both use the same tokens.
+	VaultClients *vault.ClientCache
+
+	// RejectInlineTokens refuses the deprecated inline vaultToken fields
+	RejectInlineTokens bool
+
+	// ClusterResourceNamespace is where Secrets, ConfigMaps and ServiceAccounts
+	// referenced by ClusterTimSecretConfigs are looked up
+	ClusterResourceNamespace string
+}
+
+// +kubebuilder:rbac:groups=secrets.tim.operator,resources=clustertimsecretconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=secrets.tim.operator,resources=clustertimsecretconfigs/status,verbs=get;update;patch
+
+// Reconcile checks the Vault token of a ClusterTimSecretConfig and keeps it alive
+func (r *ClusterTimSecretConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	// Fetch the ClusterTimSecretConfig instance
+	config := &secretsv1alpha1.ClusterTimSecretConfig{}
+	err := r.Get(ctx, req.NamespacedName, config)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("ClusterTimSecretConfig resource not found. Ignoring since object must be deleted")
+			// Drop the cached clients and their tokens
+			if r.VaultClients != nil {
+				r.VaultClients.Remove(clusterTimSecretConfigCacheID(req.Name))
+			}
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get ClusterTimSecretConfig")
+		return ctrl.Result{}, err
+	}
+
+	factory := r.vaultClientFactory()
+	return reconcileToken(ctx, r.Client, factory, config, &config.Spec.TimSecretConfigSpec, &config.Status, func() (*vaultConfig, error) {
+		return factory.configFromClusterTimSecretConfig(ctx, config)
+	})
+}
+
+// vaultClientFactory returns the factory used to build Vault clients
+func (r *ClusterTimSecretConfigReconciler) vaultClientFactory() *vaultClientFactory {
+	return &vaultClientFactory{
+		Client:                   r.Client,
+		clients:                  r.VaultClients,
+		rejectInlineTokens:       r.RejectInlineTokens,
+		clusterResourceNamespace: r.ClusterResourceNamespace,
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterTimSecretConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.VaultClients == nil {
+		r.VaultClients = vault.NewClientCache()
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		// Status updates must not trigger a new token check
+		For(&secretsv1alpha1.ClusterTimSecretConfig{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
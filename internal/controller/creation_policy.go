@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+// ManagedKeysAnnotation lists the keys a TimSecret with creationPolicy Merge
+// wrote into a Secret (comma separated), so keys removed from Vault can be
+// removed from the Secret without touching anyone else's keys
+const ManagedKeysAnnotation = "secrets.tim.operator/managed-keys"
+
+// checkSecretOwnership returns an error if the TimSecret may not write to the
+// existing Secret under its creationPolicy. Secrets managed by another live
+// TimSecret are always refused; unmanaged Secrets only with Adopt or Merge.
+func (r *TimSecretReconciler) checkSecretOwnership(ctx context.Context, ts *secretsv1alpha1.TimSecret, secret *corev1.Secret) error {
+	if managedBy(secret, ts) {
+		return nil
+	}
+
+	takeOver := ts.Spec.CreationPolicy == secretsv1alpha1.CreationPolicyAdopt ||
+		ts.Spec.CreationPolicy == secretsv1alpha1.CreationPolicyMerge
+
+	if owner, ok := ownerOf(secret); ok {
+		if !takeOver {
+			return fmt.Errorf("Secret %s/%s is managed by TimSecret %s; set creationPolicy to Adopt to take it over once that TimSecret is gone", secret.Namespace, secret.Name, owner)
+		}
+
+		// A Secret retained from a deleted TimSecret may be taken over
+		current := &secretsv1alpha1.TimSecret{}
+		err := r.Get(ctx, owner, current)
+		switch {
+		case errors.IsNotFound(err):
+			return nil
+		case err != nil:
+			return fmt.Errorf("failed to get TimSecret %s managing Secret %s/%s: %w", owner, secret.Namespace, secret.Name, err)
+		case string(current.UID) != secret.Labels[OwnerUIDLabel]:
+			return nil
+		}
+		return fmt.Errorf("Secret %s/%s is managed by TimSecret %s", secret.Namespace, secret.Name, owner)
+	}
+
+	if !takeOver {
+		return fmt.Errorf("Secret %s/%s already exists and is not managed by the operator; set creationPolicy to Adopt or Merge to take it over", secret.Namespace, secret.Name)
+	}
+	return nil
+}
+
+// applySecretData writes the Vault data into the Secret. With creationPolicy
+// Merge only the Vault keys are written and keys the TimSecret wrote before
+// but Vault no longer has are removed; otherwise the data is replaced.
+func applySecretData(secret *corev1.Secret, data map[string]string, policy secretsv1alpha1.CreationPolicy) {
+	if secret.Type == "" {
+		secret.Type = corev1.SecretTypeOpaque
+	}
+
+	if policy != secretsv1alpha1.CreationPolicyMerge {
+		secret.Data = make(map[string][]byte, len(data))
+		for k, v := range data {
+			secret.Data[k] = []byte(v)
+		}
+		delete(secret.Annotations, ManagedKeysAnnotation)
+		return
+	}
+
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte, len(data))
+	}
+	for _, key := range managedKeys(secret) {
+		if _, ok := data[key]; !ok {
+			delete(secret.Data, key)
+		}
+	}
+
+	keys := make([]string, 0, len(data))
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[ManagedKeysAnnotation] = strings.Join(keys, ",")
+}
+
+// removeManagedKeys removes the keys a merging TimSecret wrote into the Secret
+func removeManagedKeys(secret *corev1.Secret) {
+	for _, key := range managedKeys(secret) {
+		delete(secret.Data, key)
+	}
+}
+
+// managedKeys returns the keys recorded in the managed-keys annotation
+func managedKeys(secret *corev1.Secret) []string {
+	value := secret.Annotations[ManagedKeysAnnotation]
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+func TestApplySecretData(t *testing.T) {
+	secret := &corev1.Secret{
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{"tls.crt": []byte("cert"), "tls.key": []byte("key")},
+	}
+
+	applySecretData(secret, map[string]string{"password": "one", "username": "app"}, secretsv1alpha1.CreationPolicyMerge)
+	if len(secret.Data) != 4 || secret.Type != corev1.SecretTypeTLS {
+		t.Fatalf("Merge must keep foreign keys and the Secret type, got %v (%s)", secret.Data, secret.Type)
+	}
+	if got := secret.Annotations[ManagedKeysAnnotation]; got != "password,username" {
+		t.Errorf("unexpected managed keys %q", got)
+	}
+
+	applySecretData(secret, map[string]string{"password": "two"}, secretsv1alpha1.CreationPolicyMerge)
+	if _, ok := secret.Data["username"]; ok {
+		t.Error("a managed key removed from Vault must be removed from the Secret")
+	}
+	if string(secret.Data["password"]) != "two" || string(secret.Data["tls.crt"]) != "cert" {
+		t.Errorf("unexpected data %v", secret.Data)
+	}
+
+	removeManagedKeys(secret)
+	if len(secret.Data) != 2 {
+		t.Errorf("expected only foreign keys to remain, got %v", secret.Data)
+	}
+
+	applySecretData(secret, map[string]string{"password": "three"}, secretsv1alpha1.CreationPolicyOwner)
+	if len(secret.Data) != 1 || secret.Annotations[ManagedKeysAnnotation] != "" {
+		t.Errorf("Owner must replace the data, got %v and %v", secret.Data, secret.Annotations)
+	}
+}
+
+func TestCheckMergeTarget(t *testing.T) {
+	secret := &corev1.Secret{Type: corev1.SecretTypeOpaque}
+	if err := checkMergeTarget(secret, nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := checkMergeTarget(secret, &secretsv1alpha1.SecretTarget{Type: "kubernetes.io/tls"}); err == nil {
+		t.Error("expected an error for a type mismatch")
+	}
+
+	// An immutable Secret never accepts the merged keys
+	immutable := true
+	secret.Immutable = &immutable
+	if err := checkMergeTarget(secret, nil); err == nil {
+		t.Error("expected an error for an immutable Secret")
+	}
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+// driftedKeys compares the keys the TimSecret manages in the live Secret with
+// the data read from Vault and returns the keys that were changed, added or
+// removed out-of-band. The hashes are compared first so an unchanged Secret
+// costs a single pass.
+func driftedKeys(secret *corev1.Secret, data map[string]string, policy secretsv1alpha1.CreationPolicy) []string {
+	live := managedSecretData(secret, data, policy)
+	if calculateHash(live) == calculateHash(data) {
+		return nil
+	}
+
+	var keys []string
+	for k, v := range data {
+		if liveValue, ok := live[k]; !ok || liveValue != v {
+			keys = append(keys, k)
+		}
+	}
+	for k := range live {
+		if _, ok := data[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// managedSecretData returns the live values of the keys the TimSecret manages:
+// every key of the Secret, or with creationPolicy Merge only the Vault keys
+func managedSecretData(secret *corev1.Secret, data map[string]string, policy secretsv1alpha1.CreationPolicy) map[string]string {
+	live := make(map[string]string, len(data))
+	for k, v := range secret.Data {
+		if _, ok := data[k]; policy == secretsv1alpha1.CreationPolicyMerge && !ok {
+			continue
+		}
+		live[k] = string(v)
+	}
+	return live
+}
+
+// setDriftCondition records in the status whether out-of-band changes were
+// reverted. DriftDetected stays True for one sync interval after a revert, so
+// it remains visible after the Secret watch triggers the next, clean sync.
+func setDriftCondition(status *secretsv1alpha1.TimSecretStatus, secret *corev1.Secret, drifted []string, now metav1.Time, syncInterval time.Duration) {
+	if len(drifted) > 0 {
+		status.DriftCount++
+		status.LastDriftTime = &now
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    "DriftDetected",
+			Status:  metav1.ConditionTrue,
+			Reason:  "DriftReverted",
+			Message: fmt.Sprintf("Reverted out-of-band changes to keys %s of Secret %s/%s", strings.Join(drifted, ", "), secret.Namespace, secret.Name),
+		})
+		return
+	}
+
+	if status.LastDriftTime != nil && now.Sub(status.LastDriftTime.Time) < syncInterval {
+		return
+	}
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:    "DriftDetected",
+		Status:  metav1.ConditionFalse,
+		Reason:  "NoDrift",
+		Message: "Secret matches the data from Vault",
+	})
+}
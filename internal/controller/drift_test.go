@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+func TestDriftedKeys(t *testing.T) {
+	data := map[string]string{"password": "secret", "username": "app"}
+	secret := &corev1.Secret{Data: map[string][]byte{
+		"password": []byte("edited"),
+		"username": []byte("app"),
+		"extra":    []byte("added"),
+	}}
+
+	if got := driftedKeys(secret, data, secretsv1alpha1.CreationPolicyOwner); !reflect.DeepEqual(got, []string{"extra", "password"}) {
+		t.Errorf("Owner: driftedKeys() = %v", got)
+	}
+	if got := driftedKeys(secret, data, secretsv1alpha1.CreationPolicyMerge); !reflect.DeepEqual(got, []string{"password"}) {
+		t.Errorf("Merge: driftedKeys() = %v", got)
+	}
+
+	delete(secret.Data, "extra")
+	secret.Data["password"] = []byte("secret")
+	if got := driftedKeys(secret, data, secretsv1alpha1.CreationPolicyOwner); got != nil {
+		t.Errorf("expected no drift, got %v", got)
+	}
+}
+
+func TestSetDriftCondition(t *testing.T) {
+	status := &secretsv1alpha1.TimSecretStatus{}
+	secret := &corev1.Secret{}
+	now := metav1.Now()
+
+	setDriftCondition(status, secret, []string{"password"}, now, 5*time.Minute)
+	if status.DriftCount != 1 || !meta.IsStatusConditionTrue(status.Conditions, "DriftDetected") {
+		t.Fatalf("expected drift to be recorded, got %+v", status)
+	}
+
+	// The revert triggers a clean sync right away, which must keep the condition
+	setDriftCondition(status, secret, nil, metav1.NewTime(now.Add(time.Second)), 5*time.Minute)
+	if !meta.IsStatusConditionTrue(status.Conditions, "DriftDetected") {
+		t.Error("expected DriftDetected to stay True within the sync interval")
+	}
+
+	setDriftCondition(status, secret, nil, metav1.NewTime(now.Add(10*time.Minute)), 5*time.Minute)
+	if !meta.IsStatusConditionFalse(status.Conditions, "DriftDetected") || status.DriftCount != 1 {
+		t.Errorf("expected DriftDetected to be False after the sync interval, got %+v", status)
+	}
+}
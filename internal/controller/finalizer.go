@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+// TimSecretFinalizer lets the operator apply the deletion policy before a TimSecret is removed
+const TimSecretFinalizer = "secrets.tim.operator/finalizer"
+
+// operatorMetadataPrefix prefixes the labels and annotations the operator sets on Secrets
+const operatorMetadataPrefix = "secrets.tim.operator/"
+
+// finalizeTimSecret applies the deletion policy to the TimSecret's Secret and
+// removes the finalizer so the TimSecret can be deleted
+func (r *TimSecretReconciler) finalizeTimSecret(ctx context.Context, ts *secretsv1alpha1.TimSecret) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(ts, TimSecretFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	namespace := ts.Spec.Namespace
+	if namespace == "" {
+		namespace = ts.Namespace
+	}
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: ts.Spec.SecretName, Namespace: namespace}, secret)
+	switch {
+	case errors.IsNotFound(err):
+		logger.Info("Secret already gone", "name", ts.Spec.SecretName, "namespace", namespace)
+	case err != nil:
+		logger.Error(err, "Failed to get Secret")
+		return ctrl.Result{}, err
+	case !managedBy(secret, ts):
+		logger.Info("Secret is not managed by this TimSecret, leaving it untouched", "name", secret.Name, "namespace", secret.Namespace)
+	default:
+		if err := r.applyDeletionPolicy(ctx, ts, secret); err != nil {
+			logger.Error(err, "Failed to apply deletion policy", "deletionPolicy", ts.Spec.DeletionPolicy)
+			return ctrl.Result{}, err
+		}
+	}
+
+	// A patch rather than an update: the TimSecret's config or target
+	// namespace may already be gone, and a full update would be validated
+	// against them and leave the TimSecret stuck in Terminating
+	patch := client.MergeFrom(ts.DeepCopy())
+	controllerutil.RemoveFinalizer(ts, TimSecretFinalizer)
+	if err := r.Patch(ctx, ts, patch); err != nil {
+		logger.Error(err, "Failed to remove finalizer")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// applyDeletionPolicy deletes or releases the Secret of a deleted TimSecret
+func (r *TimSecretReconciler) applyDeletionPolicy(ctx context.Context, ts *secretsv1alpha1.TimSecret, secret *corev1.Secret) error {
+	logger := log.FromContext(ctx)
+
+	switch ts.Spec.DeletionPolicy {
+	case "", secretsv1alpha1.DeletionPolicyDelete:
+		// A merged-into Secret belongs to someone else; only the Vault keys go
+		if ts.Spec.CreationPolicy == secretsv1alpha1.CreationPolicyMerge {
+			removeManagedKeys(secret)
+			releaseSecret(secret, ts.UID, true)
+			if err := r.Update(ctx, secret); err != nil {
+				return fmt.Errorf("failed to remove managed keys from Secret %s/%s: %w", secret.Namespace, secret.Name, err)
+			}
+			logger.Info("Removed managed keys from Secret", "name", secret.Name, "namespace", secret.Namespace)
+			break
+		}
+
+		if err := r.Delete(ctx, secret); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete Secret %s/%s: %w", secret.Namespace, secret.Name, err)
+		}
+		logger.Info("Deleted Secret", "name", secret.Name, "namespace", secret.Namespace)
+
+	case secretsv1alpha1.DeletionPolicyRetain, secretsv1alpha1.DeletionPolicyOrphan:
+		orphan := ts.Spec.DeletionPolicy == secretsv1alpha1.DeletionPolicyOrphan
+		if !releaseSecret(secret, ts.UID, orphan) {
+			break
+		}
+		if err := r.Update(ctx, secret); err != nil {
+			return fmt.Errorf("failed to release Secret %s/%s: %w", secret.Namespace, secret.Name, err)
+		}
+		logger.Info("Released Secret", "name", secret.Name, "namespace", secret.Namespace, "deletionPolicy", ts.Spec.DeletionPolicy)
+
+	default:
+		return fmt.Errorf("unsupported deletionPolicy %q, must be Delete, Retain or Orphan", ts.Spec.DeletionPolicy)
+	}
+
+	return nil
+}
+
+// releaseSecret removes the TimSecret's owner reference from the Secret so it
+// survives garbage collection. With orphan, the operator's labels and
+// annotations are removed as well. Reports whether the Secret was changed.
+func releaseSecret(secret *corev1.Secret, owner types.UID, orphan bool) bool {
+	changed := false
+
+	refs := secret.OwnerReferences[:0]
+	for _, ref := range secret.OwnerReferences {
+		if ref.UID == owner {
+			changed = true
+			continue
+		}
+		refs = append(refs, ref)
+	}
+	secret.OwnerReferences = refs
+
+	if orphan {
+		for key := range secret.Labels {
+			if strings.HasPrefix(key, operatorMetadataPrefix) {
+				delete(secret.Labels, key)
+				changed = true
+			}
+		}
+		for key := range secret.Annotations {
+			if strings.HasPrefix(key, operatorMetadataPrefix) {
+				delete(secret.Annotations, key)
+				changed = true
+			}
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+func TestReleaseSecret(t *testing.T) {
+	newSecret := func() *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels:      map[string]string{"secrets.tim.operator/owner-name": "app", "app": "web"},
+				Annotations: map[string]string{"secrets.tim.operator/vault-path": "secret/data/app"},
+				OwnerReferences: []metav1.OwnerReference{
+					{UID: "other"},
+					{UID: "timsecret"},
+				},
+			},
+		}
+	}
+
+	retained := newSecret()
+	if !releaseSecret(retained, "timsecret", false) {
+		t.Fatal("expected the Secret to change")
+	}
+	if len(retained.OwnerReferences) != 1 || retained.OwnerReferences[0].UID != "other" {
+		t.Errorf("unexpected owner references %v", retained.OwnerReferences)
+	}
+	if len(retained.Labels) != 2 || len(retained.Annotations) != 1 {
+		t.Errorf("Retain must keep labels and annotations, got %v and %v", retained.Labels, retained.Annotations)
+	}
+	if releaseSecret(retained, "timsecret", false) {
+		t.Error("expected a released Secret to stay unchanged")
+	}
+
+	orphaned := newSecret()
+	releaseSecret(orphaned, "timsecret", true)
+	if len(orphaned.Labels) != 1 || orphaned.Labels["app"] != "web" {
+		t.Errorf("Orphan must only keep foreign labels, got %v", orphaned.Labels)
+	}
+	if len(orphaned.Annotations) != 0 {
+		t.Errorf("Orphan must remove operator annotations, got %v", orphaned.Annotations)
+	}
+}
+
+func TestFinalizeTimSecretWithoutConfig(t *testing.T) {
+	now := metav1.Now()
+	ts := &secretsv1alpha1.TimSecret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "app",
+			Namespace:         "team-a",
+			Finalizers:        []string{TimSecretFinalizer},
+			DeletionTimestamp: &now,
+		},
+		Spec: secretsv1alpha1.TimSecretSpec{
+			VaultConfigRef: &secretsv1alpha1.VaultConfigReference{Name: "deleted"},
+			SecretName:     "app-secrets",
+		},
+	}
+
+	// Full updates are validated against the deleted config, like the webhook does
+	c := newFakeClientBuilder(t).WithObjects(ts).WithInterceptorFuncs(interceptor.Funcs{
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			return fmt.Errorf("TimSecretConfig team-a/deleted not found")
+		},
+	}).Build()
+
+	r := &TimSecretReconciler{Client: c}
+	if _, err := r.finalizeTimSecret(context.Background(), ts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := c.Get(context.Background(), types.NamespacedName{Name: "app", Namespace: "team-a"}, &secretsv1alpha1.TimSecret{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected the TimSecret to be deleted once the finalizer is removed, got %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"sigs.k8s.io/yaml"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+// defaultFormatKeys are the Secret keys files are rendered into unless target.format.key is set
+var defaultFormatKeys = map[secretsv1alpha1.SecretFormatType]string{
+	secretsv1alpha1.SecretFormatDotenv:     "app.env",
+	secretsv1alpha1.SecretFormatJSON:       "config.json",
+	secretsv1alpha1.SecretFormatYAML:       "config.yaml",
+	secretsv1alpha1.SecretFormatProperties: "application.properties",
+	secretsv1alpha1.SecretFormatTOML:       "config.toml",
+}
+
+var (
+	// dotenvKeyPattern matches the variable names dotenv parsers accept
+	dotenvKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+	// tomlBareKeyPattern matches TOML keys that need no quotes
+	tomlBareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// formatKey returns the Secret key a format is rendered into
+func formatKey(format *secretsv1alpha1.SecretFormat) string {
+	if format.Key != "" {
+		return format.Key
+	}
+	return defaultFormatKeys[format.Type]
+}
+
+// renderFormat renders all keys into the single key of target.format. With
+// keepKeys the individual keys are kept next to it.
+func renderFormat(format *secretsv1alpha1.SecretFormat, data map[string]string) (map[string]string, error) {
+	if format == nil {
+		return data, nil
+	}
+
+	var (
+		file string
+		err  error
+	)
+	switch format.Type {
+	case secretsv1alpha1.SecretFormatDotenv:
+		file, err = formatDotenv(data)
+	case secretsv1alpha1.SecretFormatJSON:
+		file, err = formatJSON(data)
+	case secretsv1alpha1.SecretFormatYAML:
+		file, err = formatYAML(data)
+	case secretsv1alpha1.SecretFormatProperties:
+		file = formatProperties(data)
+	case secretsv1alpha1.SecretFormatTOML:
+		file = formatTOML(data)
+	default:
+		return nil, fmt.Errorf("unsupported target.format.type %q", format.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", format.Type, err)
+	}
+
+	key := formatKey(format)
+	if !format.KeepKeys {
+		return map[string]string{key: file}, nil
+	}
+	if _, ok := data[key]; ok {
+		return nil, fmt.Errorf("target.format.key %q collides with a key of the same name", key)
+	}
+	out := make(map[string]string, len(data)+1)
+	for k, v := range data {
+		out[k] = v
+	}
+	out[key] = file
+	return out, nil
+}
+
+// sortedKeys returns the keys of the data in order, so files render the same every sync
+func sortedKeys(data map[string]string) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatDotenv renders KEY="value" lines. Values are double quoted with
+// backslash, quote and line breaks escaped, so they are not split. Values
+// containing $ are single quoted instead: every dotenv parser reads single
+// quotes literally, while \$ is only unescaped by shell-style parsers. Such
+// values cannot also hold a single quote, backslash or line break.
+func formatDotenv(data map[string]string) (string, error) {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+	var b strings.Builder
+	for _, k := range sortedKeys(data) {
+		if !dotenvKeyPattern.MatchString(k) {
+			return "", fmt.Errorf("key %q is not a valid variable name, rename it with keyMapping", k)
+		}
+		value := data[k]
+		if !strings.Contains(value, "$") {
+			fmt.Fprintf(&b, "%s=\"%s\"\n", k, replacer.Replace(value))
+			continue
+		}
+		if strings.ContainsAny(value, "'\\\n\r") {
+			return "", fmt.Errorf("the value of key %q contains $ along with a single quote, backslash or line break, which dotenv parsers do not read alike", k)
+		}
+		fmt.Fprintf(&b, "%s='%s'\n", k, value)
+	}
+	return b.String(), nil
+}
+
+// formatJSON renders an indented JSON object. HTML characters are not escaped.
+func formatJSON(data map[string]string) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// formatYAML renders a YAML mapping, quoting values YAML would otherwise read
+// as another type
+func formatYAML(data map[string]string) (string, error) {
+	out, err := yaml.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// formatProperties renders a Java properties file the way Properties.store
+// does: separators, comment characters, leading spaces and control characters
+// are escaped, and characters outside ASCII are written as \uXXXX so the file
+// loads the same as ISO 8859-1 or UTF-8
+func formatProperties(data map[string]string) string {
+	var b strings.Builder
+	for _, k := range sortedKeys(data) {
+		b.WriteString(escapeProperty(k, true))
+		b.WriteByte('=')
+		b.WriteString(escapeProperty(data[k], false))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// escapeProperty escapes a properties key or value. In keys every space is
+// escaped, in values only leading spaces.
+func escapeProperty(s string, isKey bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == ' ':
+			if isKey || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case strings.ContainsRune(`\=:#!`, r):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			for _, unit := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&b, `\u%04X`, unit)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// formatTOML renders key = "value" lines. Keys that are not bare keys and all
+// values are written as basic strings.
+func formatTOML(data map[string]string) string {
+	var b strings.Builder
+	for _, k := range sortedKeys(data) {
+		if tomlBareKeyPattern.MatchString(k) {
+			b.WriteString(k)
+		} else {
+			b.WriteString(tomlString(k))
+		}
+		b.WriteString(" = ")
+		b.WriteString(tomlString(data[k]))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// tomlString quotes a TOML basic string
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/joho/godotenv"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+func TestRenderFormat(t *testing.T) {
+	data := map[string]string{
+		"DB_PASSWORD": `p"a$s\w` + "\nord",
+		"GREETING":    " héllo=world",
+	}
+
+	tests := []struct {
+		format secretsv1alpha1.SecretFormatType
+		key    string
+		want   string
+	}{
+		{secretsv1alpha1.SecretFormatJSON, "config.json", "{\n  \"DB_PASSWORD\": \"p\\\"a$s\\\\w\\nord\",\n  \"GREETING\": \" héllo=world\"\n}\n"},
+		{secretsv1alpha1.SecretFormatYAML, "config.yaml", "DB_PASSWORD: |-\n  p\"a$s\\w\n  ord\nGREETING: ' héllo=world'\n"},
+		{secretsv1alpha1.SecretFormatProperties, "application.properties", "DB_PASSWORD=p\"a$s\\\\w\\nord\nGREETING=\\ h\\u00E9llo\\=world\n"},
+		{secretsv1alpha1.SecretFormatTOML, "config.toml", "DB_PASSWORD = \"p\\\"a$s\\\\w\\nord\"\nGREETING = \" héllo=world\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			out, err := renderFormat(&secretsv1alpha1.SecretFormat{Type: tt.format}, data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(out) != 1 {
+				t.Errorf("expected only %s, got %v", tt.key, out)
+			}
+			if out[tt.key] != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", out[tt.key], tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDotenv(t *testing.T) {
+	data := map[string]string{
+		"DB_PASSWORD": `p"a\s` + "\nw'ord",
+		"API_KEY":     "a$b${c}",
+		"GREETING":    " héllo=world",
+	}
+
+	out, err := formatDotenv(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "API_KEY='a$b${c}'\nDB_PASSWORD=\"p\\\"a\\\\s\\nw'ord\"\nGREETING=\" héllo=world\"\n"
+	if out != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+
+	// Values, $ included, read back unchanged
+	parsed, err := godotenv.Unmarshal(out)
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", out, err)
+	}
+	for k, v := range data {
+		if parsed[k] != v {
+			t.Errorf("%s: got %q, want %q", k, parsed[k], v)
+		}
+	}
+
+	if _, err := formatDotenv(map[string]string{"PASSWORD": "it's $5"}); err == nil {
+		t.Error("expected an error for a value no quoting keeps literal")
+	}
+}
+
+func TestRenderFormatKeepKeys(t *testing.T) {
+	format := &secretsv1alpha1.SecretFormat{Type: secretsv1alpha1.SecretFormatTOML, Key: "app.toml", KeepKeys: true}
+
+	out, err := renderFormat(format, map[string]string{"db.host": "db"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out["db.host"] != "db" || out["app.toml"] != "\"db.host\" = \"db\"\n" {
+		t.Errorf("unexpected data %v", out)
+	}
+
+	if _, err := renderFormat(format, map[string]string{"app.toml": "x"}); err == nil {
+		t.Error("expected an error for a key colliding with the file")
+	}
+	if _, err := renderFormat(&secretsv1alpha1.SecretFormat{Type: secretsv1alpha1.SecretFormatDotenv}, map[string]string{"db-password": "x"}); err == nil {
+		t.Error("expected an error for an invalid variable name")
+	}
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+	"github.com/renatoruis/timvault-operator/internal/vault"
+)
+
+// Provenance annotations record where the data of a generated Secret came from.
+// Together with the owner metadata they name the TimSecret that wrote it.
+const (
+	// VaultPathAnnotation holds the Vault paths the data was read from
+	VaultPathAnnotation = "secrets.tim.operator/vault-path"
+
+	// VaultKVVersionAnnotation holds the version of the KV engine, 1 or 2
+	VaultKVVersionAnnotation = "secrets.tim.operator/vault-kv-version"
+
+	// VaultVersionAnnotation holds the KV v2 version of the secret that was read
+	VaultVersionAnnotation = "secrets.tim.operator/vault-version"
+
+	// SyncedAtAnnotation holds the time the Secret was last written (RFC 3339)
+	SyncedAtAnnotation = "secrets.tim.operator/synced-at"
+)
+
+// updateSecretMetadata sets the owner, template and provenance metadata of the
+// Secret and reports whether anything changed. The sync time is not included,
+// it is only set when the Secret is written anyway.
+func updateSecretMetadata(secret *corev1.Secret, ts *secretsv1alpha1.TimSecret, sources []*vault.Secret) bool {
+	changed := setOwnerMetadata(secret, ts)
+	if applyTemplateMetadata(secret, ts.Spec.Target) {
+		changed = true
+	}
+	if setProvenance(secret, sources) {
+		changed = true
+	}
+	return changed
+}
+
+// applyTemplateMetadata adds the labels and annotations of target.template.
+// Keys with the operator's prefix are reserved and never overwritten.
+func applyTemplateMetadata(secret *corev1.Secret, target *secretsv1alpha1.SecretTarget) bool {
+	if target == nil || target.Template == nil {
+		return false
+	}
+
+	changed := false
+	set := func(m *map[string]string, values map[string]string) {
+		for key, value := range values {
+			if strings.HasPrefix(key, operatorMetadataPrefix) {
+				continue
+			}
+			if *m == nil {
+				*m = map[string]string{}
+			}
+			if current, ok := (*m)[key]; !ok || current != value {
+				(*m)[key] = value
+				changed = true
+			}
+		}
+	}
+
+	set(&secret.Labels, target.Template.Metadata.Labels)
+	set(&secret.Annotations, target.Template.Metadata.Annotations)
+	return changed
+}
+
+// setProvenance records the Vault paths and versions the data was read from.
+// With several sources the annotations hold comma separated values in source
+// order; KV v1 sources have an empty version.
+func setProvenance(secret *corev1.Secret, sources []*vault.Secret) bool {
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+
+	paths := make([]string, len(sources))
+	kvVersions := make([]string, len(sources))
+	versions := make([]string, len(sources))
+	versioned := false
+	for i, source := range sources {
+		paths[i] = source.Path
+		kvVersions[i] = strconv.Itoa(source.KVVersion)
+		if source.Version > 0 {
+			versions[i] = strconv.Itoa(source.Version)
+			versioned = true
+		}
+	}
+
+	changed := false
+	values := map[string]string{
+		VaultPathAnnotation:      strings.Join(paths, ","),
+		VaultKVVersionAnnotation: strings.Join(kvVersions, ","),
+	}
+	if versioned {
+		values[VaultVersionAnnotation] = strings.Join(versions, ",")
+	} else if _, ok := secret.Annotations[VaultVersionAnnotation]; ok {
+		delete(secret.Annotations, VaultVersionAnnotation)
+		changed = true
+	}
+
+	for key, value := range values {
+		if secret.Annotations[key] != value {
+			secret.Annotations[key] = value
+			changed = true
+		}
+	}
+	return changed
+}
+
+// markSynced records the time the Secret is written
+func markSynced(secret *corev1.Secret, now time.Time) {
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[SyncedAtAnnotation] = now.UTC().Format(time.RFC3339)
+}
+
+// setImmutable marks the Secret immutable when the target asks for it and
+// reports whether that changed the Secret. An immutable Secret cannot be made
+// mutable again; such Secrets are recreated instead.
+func setImmutable(secret *corev1.Secret, target *secretsv1alpha1.SecretTarget) bool {
+	if target == nil || !target.Immutable || isImmutable(secret) {
+		return false
+	}
+	immutable := true
+	secret.Immutable = &immutable
+	return true
+}
+
+// isImmutable reports whether the Secret is immutable
+func isImmutable(secret *corev1.Secret) bool {
+	return secret.Immutable != nil && *secret.Immutable
+}
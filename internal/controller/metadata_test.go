@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+	"github.com/renatoruis/timvault-operator/internal/vault"
+)
+
+func TestApplyTemplateMetadata(t *testing.T) {
+	target := &secretsv1alpha1.SecretTarget{
+		Template: &secretsv1alpha1.SecretTemplate{
+			Metadata: secretsv1alpha1.SecretTemplateMetadata{
+				Labels:      map[string]string{"backup": "true", OwnerUIDLabel: "forged"},
+				Annotations: map[string]string{"reloader.stakater.com/match": "true"},
+			},
+		},
+	}
+	secret := &corev1.Secret{}
+
+	if !applyTemplateMetadata(secret, target) {
+		t.Fatal("expected the template metadata to be added")
+	}
+	if secret.Labels["backup"] != "true" || secret.Annotations["reloader.stakater.com/match"] != "true" {
+		t.Errorf("unexpected metadata %v %v", secret.Labels, secret.Annotations)
+	}
+	if _, ok := secret.Labels[OwnerUIDLabel]; ok {
+		t.Error("reserved labels must not be set from the template")
+	}
+	if applyTemplateMetadata(secret, target) {
+		t.Error("expected the template metadata to be unchanged")
+	}
+}
+
+func TestSetProvenance(t *testing.T) {
+	secret := &corev1.Secret{}
+
+	if !setProvenance(secret, []*vault.Secret{{Path: "secret/data/app", KVVersion: 2, Version: 7}}) {
+		t.Fatal("expected provenance to be added")
+	}
+	if secret.Annotations[VaultVersionAnnotation] != "7" || secret.Annotations[VaultKVVersionAnnotation] != "2" {
+		t.Errorf("unexpected annotations %v", secret.Annotations)
+	}
+	if setProvenance(secret, []*vault.Secret{{Path: "secret/data/app", KVVersion: 2, Version: 7}}) {
+		t.Error("expected provenance to be unchanged")
+	}
+
+	if !setProvenance(secret, []*vault.Secret{{Path: "kv/app", KVVersion: 1}}) {
+		t.Fatal("expected provenance to change")
+	}
+	if _, ok := secret.Annotations[VaultVersionAnnotation]; ok {
+		t.Error("KV v1 secrets have no version")
+	}
+	if secret.Annotations[VaultPathAnnotation] != "kv/app" {
+		t.Errorf("unexpected path %q", secret.Annotations[VaultPathAnnotation])
+	}
+
+	setProvenance(secret, []*vault.Secret{{Path: "kv/shared", KVVersion: 1}, {Path: "secret/data/app", KVVersion: 2, Version: 3}})
+	if secret.Annotations[VaultPathAnnotation] != "kv/shared,secret/data/app" || secret.Annotations[VaultVersionAnnotation] != ",3" {
+		t.Errorf("unexpected annotations for several sources %v", secret.Annotations)
+	}
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+// Secrets written by a TimSecret are tracked with labels and annotations
+// rather than owner references, which cannot point across namespaces
+const (
+	// OwnerUIDLabel holds the UID of the TimSecret managing a Secret
+	OwnerUIDLabel = "secrets.tim.operator/owner-uid"
+
+	// OwnerNamespaceLabel holds the namespace of the TimSecret managing a Secret
+	OwnerNamespaceLabel = "secrets.tim.operator/owner-namespace"
+
+	// OwnerNameAnnotation holds the name of the TimSecret managing a Secret.
+	// Names may exceed the 63 characters allowed in label values.
+	OwnerNameAnnotation = "secrets.tim.operator/owner-name"
+)
+
+// setOwnerMetadata marks the Secret as managed by the TimSecret and reports
+// whether anything changed
+func setOwnerMetadata(secret *corev1.Secret, ts *secretsv1alpha1.TimSecret) bool {
+	if secret.Labels == nil {
+		secret.Labels = map[string]string{}
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+
+	changed := secret.Labels[OwnerUIDLabel] != string(ts.UID) ||
+		secret.Labels[OwnerNamespaceLabel] != ts.Namespace ||
+		secret.Annotations[OwnerNameAnnotation] != ts.Name
+
+	secret.Labels[OwnerUIDLabel] = string(ts.UID)
+	secret.Labels[OwnerNamespaceLabel] = ts.Namespace
+	secret.Annotations[OwnerNameAnnotation] = ts.Name
+	return changed
+}
+
+// ownerOf returns the TimSecret recorded in the Secret's owner metadata
+func ownerOf(secret client.Object) (types.NamespacedName, bool) {
+	namespace := secret.GetLabels()[OwnerNamespaceLabel]
+	name := secret.GetAnnotations()[OwnerNameAnnotation]
+	if namespace == "" || name == "" {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, true
+}
+
+// managedBy reports whether the Secret is managed by the TimSecret. Secrets
+// written before ownership labels were introduced are recognised by their
+// controller reference; unlabeled Secrets in other namespaces never are.
+func managedBy(secret *corev1.Secret, ts *secretsv1alpha1.TimSecret) bool {
+	if uid, ok := secret.Labels[OwnerUIDLabel]; ok {
+		return uid == string(ts.UID)
+	}
+
+	owner := metav1.GetControllerOf(secret)
+	return owner != nil && owner.UID == ts.UID
+}
+
+// managedSecretPredicate passes only Secrets carrying the owner label
+var managedSecretPredicate = predicate.NewPredicateFuncs(func(obj client.Object) bool {
+	_, ok := obj.GetLabels()[OwnerUIDLabel]
+	return ok
+})
+
+// secretToTimSecret enqueues the TimSecret managing a Secret, in any namespace
+func secretToTimSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	owner, ok := ownerOf(obj)
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: owner}}
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+func TestOwnerMetadata(t *testing.T) {
+	ts := &secretsv1alpha1.TimSecret{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ci", UID: "uid-1"}}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "payments"}}
+
+	if managedBy(secret, ts) {
+		t.Error("a Secret the TimSecret never synced must not be managed by it")
+	}
+
+	// Having synced another Secret does not make an unlabeled one managed,
+	// e.g. after secretName or the target namespace changed
+	synced := ts.DeepCopy()
+	synced.Status.SecretHash = "hash"
+	if managedBy(secret, synced) {
+		t.Error("an unlabeled Secret in another namespace must not be managed by the TimSecret")
+	}
+	if !setOwnerMetadata(secret, ts) {
+		t.Error("expected owner metadata to be added")
+	}
+	if setOwnerMetadata(secret, ts) {
+		t.Error("expected owner metadata to be unchanged")
+	}
+
+	owner, ok := ownerOf(secret)
+	if !ok || owner != (types.NamespacedName{Namespace: "ci", Name: "app"}) {
+		t.Errorf("ownerOf() = %v, %v", owner, ok)
+	}
+	if !managedBy(secret, ts) {
+		t.Error("expected the Secret to be managed by the TimSecret")
+	}
+
+	recreated := ts.DeepCopy()
+	recreated.UID = "uid-2"
+	recreated.Status.SecretHash = "hash"
+	if managedBy(secret, recreated) {
+		t.Error("a TimSecret with another UID must not manage the Secret")
+	}
+}
@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+	"github.com/renatoruis/timvault-operator/internal/vault"
+)
+
+// checkPath returns an error unless TimSecrets in the namespace may read the
+// Vault path, which belongs to mount, through this configuration
+func (cfg *vaultConfig) checkPath(namespace, path string, mount *vault.Mount) error {
+	allowed, err := pathAllowed(cfg.allowedPaths, namespace, path, mount)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("vault path %q is not allowed for namespace %s", path, namespace)
+	}
+	return nil
+}
+
+// checkListPath returns an error unless TimSecrets in the namespace may read
+// any path directly below the prefix, so that listing it does not reveal the
+// names of secrets they could not read. The "+" stands for any one segment and
+// only matches rules that allow all of them.
+func (cfg *vaultConfig) checkListPath(namespace, prefix string, mount *vault.Mount) error {
+	allowed, err := pathAllowed(cfg.allowedPaths, namespace, strings.TrimRight(prefix, "/")+"/+", mount)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("vault path %q may not be listed for namespace %s", prefix, namespace)
+	}
+	return nil
+}
+
+// checkPathBeforeLookup returns an error unless TimSecrets in the namespace
+// may read the Vault path under some mount it could belong to. It runs before
+// the mount is looked up, so a path no rule can allow never reaches Vault or
+// the mount cache; checkPath applies the rules exactly once the mount is known.
+func (cfg *vaultConfig) checkPathBeforeLookup(namespace, path string) error {
+	allowed, err := pathAllowedOnAnyMount(cfg.allowedPaths, namespace, path)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("vault path %q is not allowed for namespace %s", path, namespace)
+	}
+	return nil
+}
+
+// checkListPathBeforeLookup is checkListPath for a prefix whose mount is not
+// known yet
+func (cfg *vaultConfig) checkListPathBeforeLookup(namespace, prefix string) error {
+	allowed, err := pathAllowedOnAnyMount(cfg.allowedPaths, namespace, strings.TrimRight(prefix, "/")+"/+")
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("vault path %q may not be listed for namespace %s", prefix, namespace)
+	}
+	return nil
+}
+
+// pathAllowedOnAnyMount reports whether pathAllowed allows the path as
+// written or as part of a KV v2 mount at any of its prefixes, one of which is
+// its actual mount
+func pathAllowedOnAnyMount(rules []secretsv1alpha1.AllowedPathRule, namespace, path string) (bool, error) {
+	allowed, err := pathAllowed(rules, namespace, path, nil)
+	if err != nil || allowed {
+		return allowed, err
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 1; i < len(segments); i++ {
+		mount := &vault.Mount{Path: strings.Join(segments[:i], "/") + "/", KVVersion: 2}
+		if allowed, err := pathAllowed(rules, namespace, path, mount); err != nil || allowed {
+			return allowed, err
+		}
+	}
+	return false, nil
+}
+
+// pathAllowed reports whether a namespace may read a Vault path under the
+// given rules. Without rules every path is allowed. With the path's mount, the
+// path and the rules are compared in the form Vault policies use, so on KV v2
+// the logical path secret/app matches the rule secret/data/app and the other
+// way round. Rules are also matched as written, for wildcards standing for the
+// data/ segment.
+func pathAllowed(rules []secretsv1alpha1.AllowedPathRule, namespace, path string, mount *vault.Mount) (bool, error) {
+	if len(rules) == 0 {
+		return true, nil
+	}
+
+	// Never let a relative segment escape an allowed prefix
+	for _, segment := range strings.Split(path, "/") {
+		if segment == ".." || segment == "." {
+			return false, nil
+		}
+	}
+	if mount != nil {
+		path = mount.PolicyPath(path)
+	}
+
+	for _, rule := range rules {
+		if len(rule.Namespaces) > 0 && !containsString(rule.Namespaces, namespace) {
+			continue
+		}
+
+		for _, pattern := range rule.Paths {
+			rendered, err := renderNamespaceTemplate("allowedPaths", pattern, namespace)
+			if err != nil {
+				return false, err
+			}
+			if matchVaultPath(rendered, path) || (mount != nil && matchVaultPath(mount.PolicyPath(rendered), path)) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// matchVaultPath matches a path against a Vault policy style pattern:
+// "+" matches exactly one segment and a trailing "*" matches any suffix
+func matchVaultPath(pattern, path string) bool {
+	pattern = strings.Trim(strings.TrimSpace(pattern), "/")
+	path = strings.Trim(path, "/")
+
+	glob := strings.HasSuffix(pattern, "*")
+	pattern = strings.TrimSuffix(pattern, "*")
+
+	patternSegments := strings.Split(pattern, "/")
+	pathSegments := strings.Split(path, "/")
+
+	for i, segment := range patternSegments {
+		if i >= len(pathSegments) {
+			return false
+		}
+
+		if glob && i == len(patternSegments)-1 && segment != "+" {
+			return strings.HasPrefix(pathSegments[i], segment)
+		}
+		if segment != "+" && segment != pathSegments[i] {
+			return false
+		}
+	}
+
+	return glob || len(pathSegments) == len(patternSegments)
+}
+
+// containsString reports whether a slice contains a string
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"testing"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+	"github.com/renatoruis/timvault-operator/internal/vault"
+)
+
+func TestMatchVaultPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "secret/data/app", path: "secret/data/app", want: true},
+		{pattern: "secret/data/app", path: "secret/data/app/db", want: false},
+		{pattern: "secret/data/app/*", path: "secret/data/app/db", want: true},
+		{pattern: "secret/data/app/*", path: "secret/data/app", want: false},
+		{pattern: "secret/data/app*", path: "secret/data/app-v2", want: true},
+		{pattern: "secret/+/app", path: "secret/data/app", want: true},
+		{pattern: "secret/+/app", path: "secret/data/x/app", want: false},
+		{pattern: "/secret/data/app/", path: "secret/data/app", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := matchVaultPath(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchVaultPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestPathAllowed(t *testing.T) {
+	rules := []secretsv1alpha1.AllowedPathRule{
+		{Paths: []string{"secret/data/{{ .Namespace }}/*"}},
+		{Namespaces: []string{"platform"}, Paths: []string{"secret/data/shared/*"}},
+	}
+
+	tests := []struct {
+		namespace string
+		path      string
+		want      bool
+	}{
+		{namespace: "team-a", path: "secret/data/team-a/db", want: true},
+		{namespace: "team-a", path: "secret/data/team-b/db", want: false},
+		{namespace: "team-a", path: "secret/data/team-a/../team-b/db", want: false},
+		{namespace: "team-a", path: "secret/data/shared/tls", want: false},
+		{namespace: "platform", path: "secret/data/shared/tls", want: true},
+	}
+
+	for _, tt := range tests {
+		got, err := pathAllowed(rules, tt.namespace, tt.path, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("pathAllowed(%q, %q) = %v, want %v", tt.namespace, tt.path, got, tt.want)
+		}
+	}
+
+	if allowed, _ := pathAllowed(nil, "team-a", "secret/data/anything", nil); !allowed {
+		t.Error("expected every path to be allowed without rules")
+	}
+}
+
+func TestPathAllowedKVv2(t *testing.T) {
+	v2 := &vault.Mount{Path: "secret/", KVVersion: 2}
+	v1 := &vault.Mount{Path: "secret/", KVVersion: 1}
+
+	tests := []struct {
+		rule  string
+		path  string
+		mount *vault.Mount
+		want  bool
+	}{
+		{rule: "secret/data/team-a/*", path: "secret/team-a/db", mount: v2, want: true},
+		{rule: "secret/data/team-a/*", path: "secret/data/team-a/db", mount: v2, want: true},
+		{rule: "secret/team-a/*", path: "secret/data/team-a/db", mount: v2, want: true},
+		{rule: "secret/team-a/*", path: "secret/team-a/db", mount: v2, want: true},
+		{rule: "secret/+/team-a/*", path: "secret/team-a/db", mount: v2, want: true},
+		{rule: "secret/data/team-a/*", path: "secret/team-b/db", mount: v2, want: false},
+		// On KV v1 data is an ordinary segment
+		{rule: "secret/data/team-a/*", path: "secret/team-a/db", mount: v1, want: false},
+		{rule: "secret/data/team-a/*", path: "secret/team-a/db", mount: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		rules := []secretsv1alpha1.AllowedPathRule{{Paths: []string{tt.rule}}}
+		got, err := pathAllowed(rules, "team-a", tt.path, tt.mount)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("pathAllowed(%q, %q) = %v, want %v", tt.rule, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCheckListPath(t *testing.T) {
+	cfg := &vaultConfig{allowedPaths: []secretsv1alpha1.AllowedPathRule{
+		{Paths: []string{"secret/data/{{ .Namespace }}/*", "secret/data/shared/+", "secret/data/apps/db"}},
+	}}
+
+	tests := []struct {
+		prefix string
+		want   bool
+	}{
+		{prefix: "secret/data/team-a", want: true},
+		{prefix: "secret/data/team-a/", want: true},
+		{prefix: "secret/data/team-a/nested", want: true},
+		{prefix: "secret/data/shared", want: true},
+		{prefix: "secret/data/team-b", want: false},
+		{prefix: "secret/data/apps", want: false},
+		{prefix: "secret/data", want: false},
+	}
+
+	for _, tt := range tests {
+		if err := cfg.checkListPath("team-a", tt.prefix, nil); (err == nil) != tt.want {
+			t.Errorf("checkListPath(%q) = %v, want allowed %v", tt.prefix, err, tt.want)
+		}
+	}
+
+	if err := cfg.checkListPath("team-a", "secret/team-a", &vault.Mount{Path: "secret/", KVVersion: 2}); err != nil {
+		t.Errorf("expected the logical prefix to be listable, got %v", err)
+	}
+	if err := (&vaultConfig{}).checkListPath("team-a", "secret/data", nil); err != nil {
+		t.Errorf("expected every prefix to be listable without rules, got %v", err)
+	}
+}
+
+func TestCheckPathBeforeLookup(t *testing.T) {
+	cfg := &vaultConfig{allowedPaths: []secretsv1alpha1.AllowedPathRule{
+		{Paths: []string{"secret/data/{{ .Namespace }}/*"}},
+	}}
+
+	// Paths allowed under some mount still need the exact check after the lookup
+	for _, path := range []string{"secret/data/team-a/db", "secret/team-a/db"} {
+		if err := cfg.checkPathBeforeLookup("team-a", path); err != nil {
+			t.Errorf("checkPathBeforeLookup(%q) returned unexpected error: %v", path, err)
+		}
+	}
+
+	// Paths no mount can make allowed never reach Vault
+	for _, path := range []string{"secret/team-b/db", "kv/team-a/db", "secret/data/team-a/../team-b/db"} {
+		if err := cfg.checkPathBeforeLookup("team-a", path); err == nil {
+			t.Errorf("checkPathBeforeLookup(%q) expected an error", path)
+		}
+	}
+
+	if err := cfg.checkListPathBeforeLookup("team-a", "secret/team-a"); err != nil {
+		t.Errorf("expected the logical prefix to be listable, got %v", err)
+	}
+	if err := cfg.checkListPathBeforeLookup("team-a", "secret"); err == nil {
+		t.Error("expected the mount itself not to be listable")
+	}
+}
@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+// dockerConfigFields are the Vault keys assembled into a .dockerconfigjson
+var dockerConfigFields = []string{"registry", "username", "password", "email"}
+
+// targetSecretType returns the type of the Secret generated for a TimSecret
+func targetSecretType(target *secretsv1alpha1.SecretTarget) corev1.SecretType {
+	if target == nil || target.Type == "" {
+		return corev1.SecretTypeOpaque
+	}
+	return target.Type
+}
+
+// renderSecretData turns the data read from Vault into the data of the
+// generated Secret: keys are mapped, templates and the format are rendered and
+// the result is checked against the requirements of the Secret type
+func renderSecretData(target *secretsv1alpha1.SecretTarget, data map[string]string) (map[string]string, error) {
+	if target == nil {
+		return data, nil
+	}
+
+	out, err := mapKeys(data, target.KeyMapping)
+	if err != nil {
+		return nil, err
+	}
+	if out, err = renderDataTemplates(target.Template, out); err != nil {
+		return nil, err
+	}
+	if out, err = renderFormat(target.Format, out); err != nil {
+		return nil, err
+	}
+
+	switch secretType := targetSecretType(target); secretType {
+	case corev1.SecretTypeOpaque:
+	case corev1.SecretTypeTLS:
+		err = validateTLSData(out)
+	case corev1.SecretTypeDockerConfigJson:
+		err = buildDockerConfigJSON(out)
+	case corev1.SecretTypeBasicAuth:
+		if out[corev1.BasicAuthUsernameKey] == "" && out[corev1.BasicAuthPasswordKey] == "" {
+			err = fmt.Errorf("requires %s or %s", corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey)
+		}
+	case corev1.SecretTypeSSHAuth:
+		err = validateSSHData(out)
+	default:
+		return nil, fmt.Errorf("unsupported target.type %q", secretType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Vault data is not a valid %s Secret: %w", targetSecretType(target), err)
+	}
+
+	return out, nil
+}
+
+// mapKeys copies the data, renaming Vault keys to the Secret keys mapped to them
+func mapKeys(data map[string]string, mapping map[string]string) (map[string]string, error) {
+	out := make(map[string]string, len(data))
+	for k, v := range data {
+		out[k] = v
+	}
+
+	for secretKey, vaultKey := range mapping {
+		if _, ok := data[vaultKey]; !ok {
+			return nil, fmt.Errorf("key %q mapped to %q not found in Vault data", vaultKey, secretKey)
+		}
+	}
+	// Remove all mapped Vault keys before setting the Secret keys, so keys can be swapped
+	for secretKey, vaultKey := range mapping {
+		if secretKey != vaultKey {
+			delete(out, vaultKey)
+		}
+	}
+	for secretKey, vaultKey := range mapping {
+		out[secretKey] = data[vaultKey]
+	}
+
+	return out, nil
+}
+
+// validateTLSData checks that tls.crt and tls.key form a valid key pair and
+// that ca.crt, if present, holds PEM certificates
+func validateTLSData(data map[string]string) error {
+	cert, key := data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey]
+	if cert == "" || key == "" {
+		return fmt.Errorf("requires %s and %s", corev1.TLSCertKey, corev1.TLSPrivateKeyKey)
+	}
+	if _, err := tls.X509KeyPair([]byte(cert), []byte(key)); err != nil {
+		return fmt.Errorf("%s and %s are not a valid key pair: %w", corev1.TLSCertKey, corev1.TLSPrivateKeyKey, err)
+	}
+
+	if ca, ok := data[corev1.ServiceAccountRootCAKey]; ok {
+		if !x509.NewCertPool().AppendCertsFromPEM([]byte(ca)) {
+			return fmt.Errorf("%s does not contain a PEM certificate", corev1.ServiceAccountRootCAKey)
+		}
+	}
+	return nil
+}
+
+// buildDockerConfigJSON assembles .dockerconfigjson from the registry,
+// username, password and email keys, or validates an existing .dockerconfigjson
+func buildDockerConfigJSON(data map[string]string) error {
+	if existing, ok := data[corev1.DockerConfigJsonKey]; ok {
+		var config struct {
+			Auths map[string]json.RawMessage `json:"auths"`
+		}
+		if err := json.Unmarshal([]byte(existing), &config); err != nil {
+			return fmt.Errorf("%s is not valid JSON: %w", corev1.DockerConfigJsonKey, err)
+		}
+		if len(config.Auths) == 0 {
+			return fmt.Errorf("%s has no auths", corev1.DockerConfigJsonKey)
+		}
+		return nil
+	}
+
+	registry, username, password := data["registry"], data["username"], data["password"]
+	if registry == "" || username == "" || password == "" {
+		return fmt.Errorf("requires %s, or registry, username and password", corev1.DockerConfigJsonKey)
+	}
+
+	entry := map[string]string{
+		"username": username,
+		"password": password,
+		"auth":     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+	}
+	if email := data["email"]; email != "" {
+		entry["email"] = email
+	}
+
+	config, err := json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{registry: entry},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, field := range dockerConfigFields {
+		delete(data, field)
+	}
+	data[corev1.DockerConfigJsonKey] = string(config)
+	return nil
+}
+
+// validateSSHData checks that ssh-privatekey holds a private key. Encrypted
+// keys are accepted since they cannot be parsed without their passphrase.
+func validateSSHData(data map[string]string) error {
+	key := data[corev1.SSHAuthPrivateKey]
+	if key == "" {
+		return fmt.Errorf("requires %s", corev1.SSHAuthPrivateKey)
+	}
+	if block, _ := pem.Decode([]byte(key)); block == nil {
+		return fmt.Errorf("%s is not PEM encoded", corev1.SSHAuthPrivateKey)
+	}
+
+	var missing *ssh.PassphraseMissingError
+	if _, err := ssh.ParseRawPrivateKey([]byte(key)); err != nil && !errors.As(err, &missing) {
+		return fmt.Errorf("%s is not a valid private key: %w", corev1.SSHAuthPrivateKey, err)
+	}
+	return nil
+}
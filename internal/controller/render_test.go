@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+// testKeyPair returns a PEM encoded self-signed certificate and its key
+func testKeyPair(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestRenderSecretDataTLS(t *testing.T) {
+	cert, key := testKeyPair(t)
+	target := &secretsv1alpha1.SecretTarget{
+		Type:       corev1.SecretTypeTLS,
+		KeyMapping: map[string]string{"tls.crt": "certificate", "tls.key": "private_key"},
+	}
+
+	out, err := renderSecretData(target, map[string]string{"certificate": cert, "private_key": key, "serial": "1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out["tls.crt"] != cert || out["tls.key"] != key || out["serial"] != "1" || len(out) != 3 {
+		t.Errorf("unexpected keys %v", out)
+	}
+
+	otherCert, _ := testKeyPair(t)
+	if _, err := renderSecretData(target, map[string]string{"certificate": otherCert, "private_key": key}); err == nil {
+		t.Error("expected an error for a mismatched key pair")
+	}
+	if _, err := renderSecretData(target, map[string]string{"certificate": cert}); err == nil {
+		t.Error("expected an error for a missing mapped key")
+	}
+}
+
+func TestRenderSecretDataDockerConfigJSON(t *testing.T) {
+	target := &secretsv1alpha1.SecretTarget{Type: corev1.SecretTypeDockerConfigJson}
+
+	out, err := renderSecretData(target, map[string]string{"registry": "ghcr.io", "username": "bot", "password": "pat"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out) != 1 {
+		t.Errorf("expected only %s, got %v", corev1.DockerConfigJsonKey, out)
+	}
+
+	var config struct {
+		Auths map[string]map[string]string `json:"auths"`
+	}
+	if err := json.Unmarshal([]byte(out[corev1.DockerConfigJsonKey]), &config); err != nil {
+		t.Fatal(err)
+	}
+	if auth := config.Auths["ghcr.io"]; auth["username"] != "bot" || auth["auth"] != "Ym90OnBhdA==" {
+		t.Errorf("unexpected auths %v", config.Auths)
+	}
+
+	if _, err := renderSecretData(target, map[string]string{"registry": "ghcr.io", "username": "bot"}); err == nil {
+		t.Error("expected an error without a password")
+	}
+}
+
+func TestRenderSecretDataRequiredKeys(t *testing.T) {
+	tests := []struct {
+		secretType corev1.SecretType
+		data       map[string]string
+		wantErr    bool
+	}{
+		{secretType: corev1.SecretTypeBasicAuth, data: map[string]string{"username": "app"}},
+		{secretType: corev1.SecretTypeBasicAuth, data: map[string]string{"token": "x"}, wantErr: true},
+		{secretType: corev1.SecretTypeSSHAuth, data: map[string]string{"ssh-privatekey": "not a key"}, wantErr: true},
+		{secretType: corev1.SecretTypeOpaque, data: map[string]string{"anything": "x"}},
+	}
+
+	for _, tt := range tests {
+		_, err := renderSecretData(&secretsv1alpha1.SecretTarget{Type: tt.secretType}, tt.data)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s with %v: error = %v, wantErr %v", tt.secretType, tt.data, err, tt.wantErr)
+		}
+	}
+}
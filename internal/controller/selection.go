@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+// selectSecretData picks the Vault keys written to the Secret. Without
+// spec.data and spec.dataFrom every key is written unchanged; otherwise only
+// the keys selected by them. A key produced by two dataFrom rules is an
+// error, spec.data entries take precedence over dataFrom.
+func selectSecretData(entries []secretsv1alpha1.SecretDataEntry, rules []secretsv1alpha1.SecretDataFromRule, data map[string]string) (map[string]string, error) {
+	if len(entries) == 0 && len(rules) == 0 {
+		return data, nil
+	}
+
+	out := map[string]string{}
+	sources := map[string]string{}
+	for i := range rules {
+		selected, err := applyDataFromRule(&rules[i], data)
+		if err != nil {
+			return nil, fmt.Errorf("dataFrom[%d]: %w", i, err)
+		}
+		for secretKey, vaultKey := range selected {
+			if other, ok := sources[secretKey]; ok && other != vaultKey {
+				return nil, fmt.Errorf("dataFrom[%d]: Vault keys %q and %q both become Secret key %q", i, other, vaultKey, secretKey)
+			}
+			sources[secretKey] = vaultKey
+			out[secretKey] = data[vaultKey]
+		}
+	}
+
+	for _, entry := range entries {
+		value, err := selectEntry(entry, data)
+		if err != nil {
+			return nil, fmt.Errorf("data %q: %w", entry.SecretKey, err)
+		}
+		out[entry.SecretKey] = value
+	}
+	return out, nil
+}
+
+// selectEntry returns the value of a spec.data entry
+func selectEntry(entry secretsv1alpha1.SecretDataEntry, data map[string]string) (string, error) {
+	value, ok := data[entry.RemoteKey]
+	if !ok {
+		return "", fmt.Errorf("key %q not found in Vault data", entry.RemoteKey)
+	}
+	if entry.Property == "" {
+		return value, nil
+	}
+
+	var current interface{}
+	if err := json.Unmarshal([]byte(value), &current); err != nil {
+		return "", fmt.Errorf("key %q is not a JSON object: %w", entry.RemoteKey, err)
+	}
+	for _, name := range strings.Split(entry.Property, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("property %q not found in key %q", entry.Property, entry.RemoteKey)
+		}
+		if current, ok = object[name]; !ok {
+			return "", fmt.Errorf("property %q not found in key %q", entry.Property, entry.RemoteKey)
+		}
+	}
+
+	if str, ok := current.(string); ok {
+		return str, nil
+	}
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// applyDataFromRule returns the Vault keys a dataFrom rule selects, by the
+// Secret key they are written to
+func applyDataFromRule(rule *secretsv1alpha1.SecretDataFromRule, data map[string]string) (map[string]string, error) {
+	include, err := compilePatterns(rule.Include)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include: %w", err)
+	}
+	exclude, err := compilePatterns(rule.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclude: %w", err)
+	}
+	rewrites := make([]*regexp.Regexp, len(rule.Rewrite))
+	for i, rewrite := range rule.Rewrite {
+		if rewrites[i], err = regexp.Compile(rewrite.Regexp); err != nil {
+			return nil, fmt.Errorf("invalid rewrite: %w", err)
+		}
+	}
+
+	selected := map[string]string{}
+	for vaultKey := range data {
+		if len(include) > 0 && !matchesAny(include, vaultKey) || matchesAny(exclude, vaultKey) {
+			continue
+		}
+
+		secretKey := vaultKey
+		for i, re := range rewrites {
+			secretKey = re.ReplaceAllString(secretKey, rule.Rewrite[i].Replace)
+		}
+		secretKey = rule.Prefix + convertKey(secretKey, rule.Conversion) + rule.Suffix
+		if secretKey == "" {
+			return nil, fmt.Errorf("Vault key %q becomes an empty Secret key", vaultKey)
+		}
+
+		if other, ok := selected[secretKey]; ok {
+			return nil, fmt.Errorf("Vault keys %q and %q both become Secret key %q", other, vaultKey, secretKey)
+		}
+		selected[secretKey] = vaultKey
+	}
+	return selected, nil
+}
+
+// compilePatterns compiles a list of regular expressions
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// matchesAny reports whether s matches any of the regular expressions
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// convertKey converts the case of a key name. The snake conversions split
+// words at separators and at lower-to-upper case changes, so db-password,
+// db.password and dbPassword all become DB_PASSWORD.
+func convertKey(key string, conversion secretsv1alpha1.KeyConversion) string {
+	switch conversion {
+	case secretsv1alpha1.KeyConversionUpper:
+		return strings.ToUpper(key)
+	case secretsv1alpha1.KeyConversionLower:
+		return strings.ToLower(key)
+	case secretsv1alpha1.KeyConversionUpperSnake:
+		return strings.ToUpper(snakeCase(key))
+	case secretsv1alpha1.KeyConversionLowerSnake:
+		return strings.ToLower(snakeCase(key))
+	}
+	return key
+}
+
+// snakeCase joins the words of a key name with underscores
+func snakeCase(key string) string {
+	var b strings.Builder
+	var prev rune
+	for i, r := range key {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			r = '_'
+		case i > 0 && unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
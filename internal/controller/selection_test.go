@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+func TestSelectSecretData(t *testing.T) {
+	data := map[string]string{
+		"db-password": "pw",
+		"db-user":     "app",
+		"apiToken":    "tok",
+		"internal":    "x",
+		"database":    `{"primary":{"host":"db-0","port":5432}}`,
+	}
+
+	tests := []struct {
+		name    string
+		entries []secretsv1alpha1.SecretDataEntry
+		rules   []secretsv1alpha1.SecretDataFromRule
+		want    map[string]string
+	}{
+		{
+			name: "no selection",
+			want: data,
+		},
+		{
+			name: "entries and properties",
+			entries: []secretsv1alpha1.SecretDataEntry{
+				{SecretKey: "password", RemoteKey: "db-password"},
+				{SecretKey: "host", RemoteKey: "database", Property: "primary.host"},
+				{SecretKey: "port", RemoteKey: "database", Property: "primary.port"},
+				{SecretKey: "primary.json", RemoteKey: "database", Property: "primary"},
+			},
+			want: map[string]string{"password": "pw", "host": "db-0", "port": "5432", "primary.json": `{"host":"db-0","port":5432}`},
+		},
+		{
+			name: "include, exclude and conversion",
+			rules: []secretsv1alpha1.SecretDataFromRule{{
+				Include:    []string{"^db-", "Token$"},
+				Exclude:    []string{"user"},
+				Conversion: secretsv1alpha1.KeyConversionUpperSnake,
+				Prefix:     "APP_",
+			}},
+			want: map[string]string{"APP_DB_PASSWORD": "pw", "APP_API_TOKEN": "tok"},
+		},
+		{
+			name: "rewrite with entries taking precedence",
+			rules: []secretsv1alpha1.SecretDataFromRule{{
+				Include: []string{"^db-"},
+				Rewrite: []secretsv1alpha1.KeyRewrite{{Regexp: "^db-(.*)$", Replace: "spring.datasource.$1"}},
+			}},
+			entries: []secretsv1alpha1.SecretDataEntry{{SecretKey: "spring.datasource.user", RemoteKey: "internal"}},
+			want:    map[string]string{"spring.datasource.password": "pw", "spring.datasource.user": "x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := selectSecretData(tt.entries, tt.rules, data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(out, tt.want) {
+				t.Errorf("got %v, want %v", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectSecretDataErrors(t *testing.T) {
+	data := map[string]string{"db-password": "pw", "db_password": "pw2", "plain": "text"}
+
+	tests := []struct {
+		name    string
+		entries []secretsv1alpha1.SecretDataEntry
+		rules   []secretsv1alpha1.SecretDataFromRule
+	}{
+		{name: "missing remote key", entries: []secretsv1alpha1.SecretDataEntry{{SecretKey: "a", RemoteKey: "missing"}}},
+		{name: "property of a plain value", entries: []secretsv1alpha1.SecretDataEntry{{SecretKey: "a", RemoteKey: "plain", Property: "x"}}},
+		{name: "keys colliding after conversion", rules: []secretsv1alpha1.SecretDataFromRule{{Conversion: secretsv1alpha1.KeyConversionUpperSnake}}},
+		{name: "keys colliding between rules", rules: []secretsv1alpha1.SecretDataFromRule{
+			{Include: []string{"-"}, Rewrite: []secretsv1alpha1.KeyRewrite{{Regexp: ".*", Replace: "key"}}},
+			{Include: []string{"_"}, Rewrite: []secretsv1alpha1.KeyRewrite{{Regexp: ".*", Replace: "key"}}},
+		}},
+	}
+	for _, tt := range tests {
+		if _, err := selectSecretData(tt.entries, tt.rules, data); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestConvertKey(t *testing.T) {
+	for key, want := range map[string]string{
+		"db-password": "DB_PASSWORD",
+		"db.password": "DB_PASSWORD",
+		"dbPassword":  "DB_PASSWORD",
+		"oauth2Token": "OAUTH2_TOKEN",
+		"API_KEY":     "API_KEY",
+	} {
+		if got := convertKey(key, secretsv1alpha1.KeyConversionUpperSnake); got != want {
+			t.Errorf("convertKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+	if got := convertKey("dbPassword", secretsv1alpha1.KeyConversionLowerSnake); got != "db_password" {
+		t.Errorf("unexpected lower snake case %q", got)
+	}
+}
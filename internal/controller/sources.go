@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+	"github.com/renatoruis/timvault-operator/internal/vault"
+)
+
+// sourcePaths returns the Vault paths a TimSecret reads, vaultPath first
+func sourcePaths(spec *secretsv1alpha1.TimSecretSpec) []string {
+	paths := make([]string, 0, len(spec.Sources)+1)
+	paths = append(paths, spec.VaultPath)
+	for _, source := range spec.Sources {
+		paths = append(paths, source.VaultPath)
+	}
+	return paths
+}
+
+// mergeSources merges the data of the sources in order. Keys present in
+// several sources with different values are resolved by the conflict policy;
+// equal values are not a conflict.
+func mergeSources(sources []*vault.Secret, policy secretsv1alpha1.ConflictPolicy) (map[string]string, error) {
+	if len(sources) == 1 {
+		return sources[0].Data, nil
+	}
+
+	merged := map[string]string{}
+	origin := map[string]string{}
+	var conflicts []string
+	for _, source := range sources {
+		for k, v := range source.Data {
+			if current, ok := merged[k]; ok && current != v {
+				switch policy {
+				case secretsv1alpha1.ConflictPolicyFirstWins:
+					continue
+				case secretsv1alpha1.ConflictPolicyLastWins:
+				default:
+					conflicts = append(conflicts, fmt.Sprintf("%q (%s, %s)", k, origin[k], source.Path))
+					continue
+				}
+			}
+			merged[k] = v
+			origin[k] = source.Path
+		}
+	}
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return nil, fmt.Errorf("sources disagree on keys %v; set conflictPolicy to FirstWins or LastWins to resolve them", conflicts)
+	}
+	return merged, nil
+}
+
+// sourceStatuses returns the status of the sources read at a sync
+func sourceStatuses(sources []*vault.Secret) []secretsv1alpha1.SourceStatus {
+	statuses := make([]secretsv1alpha1.SourceStatus, len(sources))
+	for i, source := range sources {
+		statuses[i] = secretsv1alpha1.SourceStatus{
+			VaultPath:      source.Path,
+			KVVersion:      source.KVVersion,
+			Version:        source.Version,
+			CurrentVersion: source.CurrentVersion,
+			CustomMetadata: source.CustomMetadata,
+		}
+	}
+	return statuses
+}
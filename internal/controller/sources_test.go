@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+	"github.com/renatoruis/timvault-operator/internal/vault"
+)
+
+func TestMergeSources(t *testing.T) {
+	sources := []*vault.Secret{
+		{Path: "secret/data/infra", Data: map[string]string{"db-host": "db", "region": "eu", "log-level": "info"}},
+		{Path: "secret/data/app", Data: map[string]string{"api-key": "k", "region": "eu", "log-level": "debug"}},
+	}
+
+	tests := []struct {
+		policy secretsv1alpha1.ConflictPolicy
+		want   map[string]string
+	}{
+		{secretsv1alpha1.ConflictPolicyFirstWins, map[string]string{"db-host": "db", "region": "eu", "log-level": "info", "api-key": "k"}},
+		{secretsv1alpha1.ConflictPolicyLastWins, map[string]string{"db-host": "db", "region": "eu", "log-level": "debug", "api-key": "k"}},
+	}
+	for _, tt := range tests {
+		merged, err := mergeSources(sources, tt.policy)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.policy, err)
+		}
+		if !reflect.DeepEqual(merged, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.policy, merged, tt.want)
+		}
+	}
+
+	// Equal values in several sources are not a conflict
+	if _, err := mergeSources(sources, secretsv1alpha1.ConflictPolicyError); err == nil {
+		t.Error("expected an error for log-level")
+	}
+	sources[1].Data["log-level"] = "info"
+	if _, err := mergeSources(sources, secretsv1alpha1.ConflictPolicyError); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
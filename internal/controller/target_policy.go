@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AllowTargetsFromAnnotation is set on a namespace to let TimSecrets from the
+// listed namespaces (comma separated) write Secrets and restart Deployments in it
+const AllowTargetsFromAnnotation = "secrets.tim.operator/allow-targets-from"
+
+// targetNamespaceAllowed reports whether a TimSecret in the source namespace
+// may write Secrets and restart Deployments in the target namespace
+func targetNamespaceAllowed(ctx context.Context, c client.Reader, source, target string) (bool, error) {
+	if target == "" || target == source {
+		return true, nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: target}, ns); err != nil {
+		return false, fmt.Errorf("failed to get Namespace %s: %w", target, err)
+	}
+
+	return allowsNamespace(ns.Annotations[AllowTargetsFromAnnotation], source), nil
+}
+
+// allowsNamespace reports whether an allow-targets-from or allow-config-from
+// annotation value names the source namespace
+func allowsNamespace(annotation, source string) bool {
+	for _, namespace := range strings.Split(annotation, ",") {
+		if strings.TrimSpace(namespace) == source {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTargetNamespace returns an error unless the TimSecret in the source
+// namespace may target the namespace
+func checkTargetNamespace(ctx context.Context, c client.Reader, allowAll bool, source, target string) error {
+	if allowAll {
+		return nil
+	}
+
+	allowed, err := targetNamespaceAllowed(ctx, c, source, target)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("namespace %s does not allow targets from namespace %s; annotate it with %s=%s",
+			target, source, AllowTargetsFromAnnotation, source)
+	}
+	return nil
+}
+
+// AllowConfigFromAnnotation is set on a namespace to let TimSecrets from the
+// listed namespaces (comma separated) use its TimSecretConfigs through the
+// deprecated vaultConfigNamespace field
+const AllowConfigFromAnnotation = "secrets.tim.operator/allow-config-from"
+
+// checkConfigNamespace returns an error unless the TimSecret in the source
+// namespace may use TimSecretConfigs in the config namespace. Such a config
+// carries the credentials and allowedPaths of its namespace, so that
+// namespace has to opt in.
+func checkConfigNamespace(ctx context.Context, c client.Reader, rejectAll bool, source, config string) error {
+	if config == "" || config == source {
+		return nil
+	}
+	if rejectAll {
+		return fmt.Errorf("vaultConfigNamespace %s differs from the TimSecret's namespace, which is disabled; use a ClusterTimSecretConfig instead", config)
+	}
+
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: config}, ns); err != nil {
+		return fmt.Errorf("failed to get Namespace %s: %w", config, err)
+	}
+	if !allowsNamespace(ns.Annotations[AllowConfigFromAnnotation], source) {
+		return fmt.Errorf("namespace %s does not allow TimSecretConfig references from namespace %s; annotate it with %s=%s or use a ClusterTimSecretConfig",
+			config, source, AllowConfigFromAnnotation, source)
+	}
+	return nil
+}
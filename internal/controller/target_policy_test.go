@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAllowsNamespace(t *testing.T) {
+	tests := []struct {
+		annotation string
+		source     string
+		want       bool
+	}{
+		{annotation: "", source: "team-a", want: false},
+		{annotation: "team-a", source: "team-a", want: true},
+		{annotation: "team-b, team-a", source: "team-a", want: true},
+		{annotation: "team-ab", source: "team-a", want: false},
+		{annotation: "*", source: "team-a", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := allowsNamespace(tt.annotation, tt.source); got != tt.want {
+			t.Errorf("allowsNamespace(%q, %q) = %v, want %v", tt.annotation, tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestCheckConfigNamespace(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClientBuilder(t).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "vault-system", Annotations: map[string]string{AllowConfigFromAnnotation: "team-a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "platform"}},
+	).Build()
+
+	if err := checkConfigNamespace(ctx, c, false, "team-a", ""); err != nil {
+		t.Errorf("unexpected error for the TimSecret's own namespace: %v", err)
+	}
+	if err := checkConfigNamespace(ctx, c, false, "team-a", "vault-system"); err != nil {
+		t.Errorf("unexpected error for a namespace that opted in: %v", err)
+	}
+
+	// Other namespaces' credentials are off limits unless they opt in
+	if err := checkConfigNamespace(ctx, c, false, "team-a", "platform"); err == nil {
+		t.Error("expected an error for a namespace that did not opt in")
+	}
+	if err := checkConfigNamespace(ctx, c, false, "team-b", "vault-system"); err == nil {
+		t.Error("expected an error for a namespace that is not listed")
+	}
+	if err := checkConfigNamespace(ctx, c, true, "team-a", "vault-system"); err == nil {
+		t.Error("expected an error when cross-namespace references are rejected")
+	}
+}
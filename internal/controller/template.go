@@ -0,0 +1,215 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+	"software.sslmate.com/src/go-pkcs12"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+// templateFuncs are the helpers available in target.template.data. Names and
+// argument order follow the sprig library used by Helm, so templates can be
+// moved between the two.
+var templateFuncs = template.FuncMap{
+	"b64enc":  func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"b64dec":  b64dec,
+	"toJson":  toJSON,
+	"toYaml":  toYAML,
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"trim":    strings.TrimSpace,
+	"quote":   func(s string) string { return fmt.Sprintf("%q", s) },
+	"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"indent":  indent,
+	"nindent": func(n int, s string) string { return "\n" + indent(n, s) },
+	"default": defaultValue,
+	"pkcs12":  encodePKCS12,
+}
+
+// parseDataTemplates parses the templates of target.template.data by Secret key
+func parseDataTemplates(data map[string]string) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template, len(data))
+	for key, text := range data {
+		tmpl, err := template.New(key).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template for key %q: %w", key, err)
+		}
+		templates[key] = tmpl
+	}
+	return templates, nil
+}
+
+// renderDataTemplates renders target.template.data over the Vault data. With
+// mergePolicy Merge the rendered keys are added to the Vault keys, otherwise
+// the Secret holds only the rendered keys.
+func renderDataTemplates(tmpl *secretsv1alpha1.SecretTemplate, data map[string]string) (map[string]string, error) {
+	if tmpl == nil || len(tmpl.Data) == 0 {
+		return data, nil
+	}
+
+	templates, err := parseDataTemplates(tmpl.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[string]string{}
+	if tmpl.MergePolicy == secretsv1alpha1.TemplateMergePolicyMerge {
+		for k, v := range data {
+			out[k] = v
+		}
+	}
+
+	for key, t := range templates {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render template for key %q: %w", key, err)
+		}
+		out[key] = buf.String()
+	}
+	return out, nil
+}
+
+// b64dec decodes a standard base64 string
+func b64dec(s string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+// toJSON encodes a value as JSON
+func toJSON(v interface{}) (string, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// toYAML encodes a value as YAML, without the trailing newline
+func toYAML(v interface{}) (string, error) {
+	encoded, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(encoded), "\n"), nil
+}
+
+// indent prefixes every line of s with n spaces
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// defaultValue returns given, or def if given is empty
+func defaultValue(def interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || isEmptyValue(given[0]) {
+		return def
+	}
+	return given[0]
+}
+
+// isEmptyValue reports whether v is nil or the zero value of its type
+func isEmptyValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	default:
+		return value.IsZero()
+	}
+}
+
+// encodePKCS12 builds a PKCS #12 keystore from a PEM certificate chain, leaf
+// first, and its PEM private key. The keystore is returned as raw bytes, so it
+// must be the whole value of a Secret key or be passed to b64enc.
+//
+// The salts and IV are derived from the inputs instead of drawn at random, so
+// the same certificate, key and password always give the same bytes and the
+// Secret hash only changes when one of them does.
+func encodePKCS12(password, certPEM, keyPEM string) (string, error) {
+	pair, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return "", fmt.Errorf("pkcs12: %w", err)
+	}
+
+	certs := make([]*x509.Certificate, 0, len(pair.Certificate))
+	for _, der := range pair.Certificate {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return "", fmt.Errorf("pkcs12: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(pair.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("pkcs12: %w", err)
+	}
+	seed := append([][]byte{[]byte(password), keyDER}, pair.Certificate...)
+
+	keystore, err := pkcs12.Modern.WithRand(newSeededReader(seed...)).Encode(pair.PrivateKey, certs[0], certs[1:], password)
+	if err != nil {
+		return "", fmt.Errorf("pkcs12: %w", err)
+	}
+	return string(keystore), nil
+}
+
+// seededReader is a deterministic io.Reader that expands a SHA-256 digest of
+// its seed in counter mode
+type seededReader struct {
+	seed    [sha256.Size]byte
+	counter uint64
+	buf     []byte
+}
+
+// newSeededReader returns a seededReader for the given parts, each of which is
+// length-prefixed so that different splits of the same bytes differ
+func newSeededReader(parts ...[]byte) *seededReader {
+	h := sha256.New()
+	for _, part := range parts {
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(len(part)))
+		h.Write(n[:])
+		h.Write(part)
+	}
+	r := &seededReader{}
+	h.Sum(r.seed[:0])
+	return r
+}
+
+// Read fills p with the next bytes of the stream. It never fails.
+func (r *seededReader) Read(p []byte) (int, error) {
+	for n := 0; n < len(p); {
+		if len(r.buf) == 0 {
+			var block [sha256.Size + 8]byte
+			copy(block[:], r.seed[:])
+			binary.BigEndian.PutUint64(block[sha256.Size:], r.counter)
+			r.counter++
+			sum := sha256.Sum256(block[:])
+			r.buf = sum[:]
+		}
+		c := copy(p[n:], r.buf)
+		r.buf = r.buf[c:]
+		n += c
+	}
+	return len(p), nil
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"testing"
+
+	"software.sslmate.com/src/go-pkcs12"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+func TestRenderDataTemplates(t *testing.T) {
+	data := map[string]string{"username": "app", "password": "s3cr3t", "db-host": "db"}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"fields", "jdbc:postgresql://{{ index . \"db-host\" }}:5432/app?user={{ .username }}", "jdbc:postgresql://db:5432/app?user=app"},
+		{"b64enc", "{{ printf \"%s:%s\" .username .password | b64enc }}", "YXBwOnMzY3IzdA=="},
+		{"upper", "{{ .username | upper }}", "APP"},
+		{"default", "{{ .port | default \"5432\" }}", "5432"},
+		{"default set", "{{ .username | default \"root\" }}", "app"},
+		{"toJson", "{{ toJson . }}", `{"db-host":"db","password":"s3cr3t","username":"app"}`},
+		{"nindent", "auth:{{ printf \"user: %s\\npass: %s\" .username .password | nindent 2 }}", "auth:\n  user: app\n  pass: s3cr3t"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := renderDataTemplates(&secretsv1alpha1.SecretTemplate{Data: map[string]string{"out": tt.template}}, data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out["out"] != tt.want {
+				t.Errorf("got %q, want %q", out["out"], tt.want)
+			}
+			if len(out) != 1 {
+				t.Errorf("expected only the templated key with mergePolicy Replace, got %v", out)
+			}
+		})
+	}
+}
+
+func TestRenderDataTemplatesMerge(t *testing.T) {
+	tmpl := &secretsv1alpha1.SecretTemplate{
+		Data:        map[string]string{".npmrc": "//registry.npmjs.org/:_authToken={{ .token }}"},
+		MergePolicy: secretsv1alpha1.TemplateMergePolicyMerge,
+	}
+
+	out, err := renderDataTemplates(tmpl, map[string]string{"token": "abc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out["token"] != "abc" || out[".npmrc"] != "//registry.npmjs.org/:_authToken=abc" {
+		t.Errorf("unexpected data %v", out)
+	}
+
+	if _, err := renderDataTemplates(&secretsv1alpha1.SecretTemplate{Data: map[string]string{"bad": "{{ .token"}}, nil); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
+
+func TestEncodePKCS12(t *testing.T) {
+	cert, key := testKeyPair(t)
+
+	out, err := renderDataTemplates(&secretsv1alpha1.SecretTemplate{
+		Data: map[string]string{"keystore.p12": "{{ pkcs12 .password .cert .key }}"},
+	}, map[string]string{"password": "changeit", "cert": cert, "key": key})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, decoded, err := pkcs12.Decode([]byte(out["keystore.p12"]), "changeit"); err != nil {
+		t.Fatalf("keystore does not decode: %v", err)
+	} else if decoded.Subject.CommonName != "test" {
+		t.Errorf("unexpected certificate %v", decoded.Subject)
+	}
+
+	again, err := encodePKCS12("changeit", cert, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again != out["keystore.p12"] {
+		t.Error("expected the same keystore for the same inputs")
+	}
+	if other, err := encodePKCS12("secret", cert, key); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if other == again {
+		t.Error("expected a different keystore for a different password")
+	}
+
+	otherCert, _ := testKeyPair(t)
+	if _, err := encodePKCS12("changeit", otherCert, key); err == nil {
+		t.Error("expected an error for a mismatched key pair")
+	}
+}
@@ -3,19 +3,28 @@ package controller
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
@@ -26,13 +35,41 @@ import (
 type TimSecretReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// VaultClients caches authenticated Vault clients per TimSecretConfig
+	VaultClients *vault.ClientCache
+
+	// RejectInlineTokens refuses the deprecated inline vaultToken fields
+	RejectInlineTokens bool
+
+	// ServiceAccountTokenAudiences are the ServiceAccount token audiences
+	// TimSecretConfigs may request, empty allows only "vault"
+	ServiceAccountTokenAudiences []string
+
+	// RejectCrossNamespaceConfig refuses vaultConfigNamespace references to
+	// TimSecretConfigs in other namespaces, even when that namespace opted in
+	RejectCrossNamespaceConfig bool
+
+	// ClusterResourceNamespace is where Secrets, ConfigMaps and ServiceAccounts
+	// referenced by ClusterTimSecretConfigs are looked up
+	ClusterResourceNamespace string
+
+	// AllowCrossNamespaceTargets lets TimSecrets write Secrets and restart
+	// Deployments in any namespace, without the target namespace opting in
+	AllowCrossNamespaceTargets bool
+
+	// Recorder emits Events on TimSecrets, e.g. when drift is reverted
+	Recorder record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=secrets.tim.operator,resources=timsecrets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=secrets.tim.operator,resources=timsecrets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=secrets.tim.operator,resources=timsecrets/finalizers,verbs=update
-// +kubebuilder:rbac:groups=secrets.tim.operator,resources=timsecretconfigs,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=serviceaccounts/token,verbs=create
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
@@ -45,7 +82,7 @@ func (r *TimSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	timSecret := &secretsv1alpha1.TimSecret{}
 	err := r.Get(ctx, req.NamespacedName, timSecret)
 	if err != nil {
-		if errors.IsNotFound(err) {
+		if apierrors.IsNotFound(err) {
 			logger.Info("TimSecret resource not found. Ignoring since object must be deleted")
 			return ctrl.Result{}, nil
 		}
@@ -53,28 +90,135 @@ func (r *TimSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
+	// Apply the deletion policy before the TimSecret goes away
+	if !timSecret.DeletionTimestamp.IsZero() {
+		return r.finalizeTimSecret(ctx, timSecret)
+	}
+
+	// The finalizer lets the deletion policy also cover Secrets in other
+	// namespaces, which owner references cannot garbage collect
+	if controllerutil.AddFinalizer(timSecret, TimSecretFinalizer) {
+		if err := r.Update(ctx, timSecret); err != nil {
+			logger.Error(err, "Failed to add finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
 	// Parse sync interval (default 5 minutes)
 	syncInterval := r.parseSyncInterval(timSecret.Spec.SyncInterval)
 
+	// The admission webhooks are optional, so invalid specs are refused here
+	// too. Combinations accepted before validation existed keep syncing as
+	// they did, with a warning, so upgrades do not break existing TimSecrets.
+	spec := timSecret.Spec.DeepCopy()
+	defaultTimSecret(spec)
+	deprecations := normalizeLegacyTimSecretSpec(spec)
+	if spec.VaultConfigNamespace != "" && spec.VaultConfigNamespace != timSecret.Namespace {
+		deprecations = append(deprecations, "spec.vaultConfigNamespace is deprecated, use a ClusterTimSecretConfig instead")
+	}
+	if setDeprecatedCondition(&timSecret.Status, deprecations) && len(deprecations) > 0 {
+		message := strings.Join(deprecations, "; ")
+		logger.Info("TimSecret uses a deprecated spec", "warnings", message)
+		if r.Recorder != nil {
+			r.Recorder.Event(timSecret, corev1.EventTypeWarning, "DeprecatedSpec", message)
+		}
+	}
+	if errs, _ := validateTimSecretSpec(spec, field.NewPath("spec")); len(errs) > 0 {
+		err := errs.ToAggregate()
+		logger.Error(err, "Invalid TimSecret spec")
+		return r.handleError(ctx, timSecret, syncInterval, err, "InvalidSpec")
+	}
+
+	// Determine namespace
+	namespace := timSecret.Spec.Namespace
+	if namespace == "" {
+		namespace = timSecret.Namespace
+	}
+
+	// Other namespaces must opt in to receive Secrets and restarts
+	if err := checkTargetNamespace(ctx, r.Client, r.AllowCrossNamespaceTargets, timSecret.Namespace, namespace); err != nil {
+		logger.Error(err, "Target namespace not allowed")
+		return r.handleError(ctx, timSecret, syncInterval, err, "TargetNamespaceNotAllowed")
+	}
+
 	// Resolve Vault configuration
-	vaultURL, vaultToken, err := r.resolveVaultConfig(ctx, timSecret)
+	vaultCfg, err := r.resolveVaultConfig(ctx, timSecret)
 	if err != nil {
 		logger.Error(err, "Failed to resolve Vault configuration")
 		return r.handleError(ctx, timSecret, syncInterval, err, "VaultConfigResolutionFailed")
 	}
 
+	// Enforce the config's path allow list before Vault is contacted, against
+	// every mount a path could belong to
+	paths := sourcePaths(&timSecret.Spec)
+	for _, path := range paths {
+		if err := vaultCfg.checkPathBeforeLookup(timSecret.Namespace, path); err != nil {
+			logger.Error(err, "Vault path not allowed")
+			return r.handleError(ctx, timSecret, syncInterval, err, "PathNotAllowed")
+		}
+	}
+
 	// Create Vault client
-	vaultClient, err := vault.NewClient(vaultURL, vaultToken)
+	vaultClient, err := r.vaultClientFactory().newVaultClient(vaultCfg)
 	if err != nil {
 		logger.Error(err, "Failed to create Vault client")
 		return r.handleError(ctx, timSecret, syncInterval, err, "VaultClientCreationFailed")
 	}
 
-	// Get secrets from Vault
-	secretData, err := vaultClient.GetSecrets(ctx, timSecret.Spec.VaultPath)
+	// Log in to Vault (no-op when a cached token is still valid)
+	if err := vaultClient.Authenticate(ctx); err != nil {
+		logger.Error(err, "Failed to authenticate to Vault")
+		return r.handleError(ctx, timSecret, syncInterval, err, "VaultAuthenticationFailed")
+	}
+
+	// Enforce the config's path allow list again against the actual mounts,
+	// so logical and data paths of KV v2 compare equal
+	for _, path := range paths {
+		mount, err := vaultClient.LookupMount(ctx, path)
+		if err != nil {
+			logger.Error(err, "Failed to look up the mount of a Vault path", "path", path)
+			return r.handleError(ctx, timSecret, syncInterval, err, "MountLookupFailed")
+		}
+		if err := vaultCfg.checkPath(timSecret.Namespace, path, mount); err != nil {
+			logger.Error(err, "Vault path not allowed")
+			return r.handleError(ctx, timSecret, syncInterval, err, "PathNotAllowed")
+		}
+	}
+
+	// Get secrets from Vault, the pinned version applying to vaultPath only
+	sources := make([]*vault.Secret, 0, len(paths))
+	for i, path := range paths {
+		version := 0
+		if i == 0 {
+			version = timSecret.Spec.Version
+		}
+		source, err := vaultClient.ReadSecretVersion(ctx, path, version)
+		if err != nil {
+			logger.Error(err, "Failed to get secrets from Vault", "path", path)
+			reason := "VaultSecretFetchFailed"
+			if errors.Is(err, vault.ErrMountLookup) {
+				reason = "MountLookupFailed"
+			}
+			return r.handleError(ctx, timSecret, syncInterval, err, reason)
+		}
+		sources = append(sources, source)
+	}
+
+	// Merge the sources into one set of keys
+	vaultData, err := mergeSources(sources, timSecret.Spec.ConflictPolicy)
 	if err != nil {
-		logger.Error(err, "Failed to get secrets from Vault")
-		return r.handleError(ctx, timSecret, syncInterval, err, "VaultSecretFetchFailed")
+		logger.Error(err, "Failed to merge Vault sources")
+		return r.handleError(ctx, timSecret, syncInterval, err, "SourceConflict")
+	}
+
+	// Select the wanted Vault keys and shape them into the target Secret's keys and type
+	secretData, err := selectSecretData(timSecret.Spec.Data, timSecret.Spec.DataFrom, vaultData)
+	if err == nil {
+		secretData, err = renderSecretData(timSecret.Spec.Target, secretData)
+	}
+	if err != nil {
+		logger.Error(err, "Failed to render Secret data")
+		return r.handleError(ctx, timSecret, syncInterval, err, "InvalidSecretData")
 	}
 
 	// Calculate hash of secret data
@@ -87,92 +231,149 @@ func (r *TimSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		"newHash", newHash,
 		"changed", oldHash != newHash)
 
-	// Determine namespace
-	namespace := timSecret.Spec.Namespace
-	if namespace == "" {
-		namespace = timSecret.Namespace
-	}
-
-	// Create or update Kubernetes Secret
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      timSecret.Spec.SecretName,
-			Namespace: namespace,
-		},
-	}
+	// Check if secret data has changed
+	secretChanged := timSecret.Status.SecretHash != newHash
 
-	secretExists := true
-	err = r.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, secret)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			secretExists = false
-		} else {
-			logger.Error(err, "Failed to get Secret")
-			return ctrl.Result{}, err
+	reason, message := "SecretSynced", "Secret successfully synced from Vault"
+	var secret *corev1.Secret
+	var drifted []string
+	if timSecret.Spec.CreationPolicy == secretsv1alpha1.CreationPolicyNone {
+		// Only Vault access is checked; the Secret is left to someone else
+		reason, message = "SecretNotManaged", "Secret read from Vault; creationPolicy None leaves the Kubernetes Secret untouched"
+		logger.Info("creationPolicy None, skipping Secret", "name", timSecret.Spec.SecretName, "namespace", namespace)
+	} else {
+		// Create or update Kubernetes Secret
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      timSecret.Spec.SecretName,
+				Namespace: namespace,
+			},
 		}
-	}
 
-	// Check if secret data has changed
-	secretChanged := timSecret.Status.SecretHash != newHash
+		secretExists := true
+		err = r.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, secret)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				secretExists = false
+			} else {
+				logger.Error(err, "Failed to get Secret")
+				return ctrl.Result{}, err
+			}
+		}
 
-	// Convert map[string]string to map[string][]byte
-	secretDataBytes := make(map[string][]byte)
-	for k, v := range secretData {
-		secretDataBytes[k] = []byte(v)
-	}
+		if !secretExists && timSecret.Spec.CreationPolicy == secretsv1alpha1.CreationPolicyMerge {
+			err := fmt.Errorf("Secret %s/%s does not exist; creationPolicy Merge only writes into existing Secrets", namespace, secret.Name)
+			logger.Error(err, "Secret to merge into not found")
+			return r.handleError(ctx, timSecret, syncInterval, err, "SecretNotFound")
+		}
 
-	// Create or update Secret only if it doesn't exist or data changed
-	if !secretExists {
-		// Create new secret
-		secret.Data = secretDataBytes
-		secret.Type = corev1.SecretTypeOpaque
+		// Never take over a Secret that belongs to someone else
+		if secretExists {
+			if err := r.checkSecretOwnership(ctx, timSecret, secret); err != nil {
+				logger.Error(err, "Secret is not managed by this TimSecret")
+				return r.handleError(ctx, timSecret, syncInterval, err, "SecretConflict")
+			}
+		}
 
-		if err := ctrl.SetControllerReference(timSecret, secret, r.Scheme); err != nil {
-			logger.Error(err, "Failed to set controller reference")
-			return ctrl.Result{}, err
+		// The type of a Secret cannot change and an immutable Secret's data
+		// cannot change, so a managed Secret is recreated instead
+		secretType := targetSecretType(timSecret.Spec.Target)
+		immutable := timSecret.Spec.Target != nil && timSecret.Spec.Target.Immutable
+		recreate := false
+		if secretExists && timSecret.Spec.CreationPolicy == secretsv1alpha1.CreationPolicyMerge {
+			if err := checkMergeTarget(secret, timSecret.Spec.Target); err != nil {
+				logger.Error(err, "Secret cannot be merged into")
+				return r.handleError(ctx, timSecret, syncInterval, err, "SecretConflict")
+			}
+		} else if secretExists && (secret.Type != secretType || isImmutable(secret) && (secretChanged || !immutable)) {
+			// Secrets being adopted belong to someone else until the first
+			// sync; they are never deleted to be replaced
+			if !managedBy(secret, timSecret) {
+				err := fmt.Errorf("Secret %s/%s is of type %s, but target.type is %s; Secrets being adopted are never recreated", namespace, secret.Name, secret.Type, secretType)
+				if secret.Type == secretType {
+					err = fmt.Errorf("Secret %s/%s is immutable; Secrets being adopted are never recreated to change their data", namespace, secret.Name)
+				}
+				logger.Error(err, "Secret cannot be adopted")
+				return r.handleError(ctx, timSecret, syncInterval, err, "SecretConflict")
+			}
+			recreate = true
 		}
-		if err := r.Create(ctx, secret); err != nil {
-			logger.Error(err, "Failed to create Secret")
-			return ctrl.Result{}, err
+
+		// Out-of-band edits are reverted even when Vault has not changed
+		if secretExists && !recreate && !secretChanged {
+			drifted = driftedKeys(secret, secretData, timSecret.Spec.CreationPolicy)
 		}
-		logger.Info("Created Secret", "name", secret.Name, "namespace", secret.Namespace)
-	} else if secretChanged {
-		// Update existing secret ONLY if data changed
-		secret.Data = secretDataBytes
-		secret.Type = corev1.SecretTypeOpaque
-
-		if err := r.Update(ctx, secret); err != nil {
-			logger.Error(err, "Failed to update Secret")
-			return ctrl.Result{}, err
+
+		// Create or update Secret only if it doesn't exist, data changed or drifted
+		if !secretExists || recreate {
+			// Build the new secret completely before replacing the old one
+			replacement := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: secret.Name, Namespace: secret.Namespace}}
+			replacement.Type = secretType
+			applySecretData(replacement, secretData, timSecret.Spec.CreationPolicy)
+			updateSecretMetadata(replacement, timSecret, sources)
+			setImmutable(replacement, timSecret.Spec.Target)
+			markSynced(replacement, time.Now())
+
+			if recreate {
+				logger.Info("Recreating Secret", "name", secret.Name, "namespace", secret.Namespace, "type", secretType, "immutable", immutable)
+				if err := r.recreateSecret(ctx, secret, replacement); err != nil {
+					logger.Error(err, "Failed to recreate Secret")
+					return ctrl.Result{}, err
+				}
+			} else if err := r.Create(ctx, replacement); err != nil {
+				logger.Error(err, "Failed to create Secret")
+				return ctrl.Result{}, err
+			}
+			secret = replacement
+			logger.Info("Created Secret", "name", secret.Name, "namespace", secret.Namespace)
+		} else if metadataChanged, immutableChanged := updateSecretMetadata(secret, timSecret, sources), setImmutable(secret, timSecret.Spec.Target); secretChanged || metadataChanged || immutableChanged || len(drifted) > 0 {
+			// Update existing secret ONLY if data, metadata, immutability or drift changed
+			applySecretData(secret, secretData, timSecret.Spec.CreationPolicy)
+			markSynced(secret, time.Now())
+
+			if err := r.Update(ctx, secret); err != nil {
+				logger.Error(err, "Failed to update Secret")
+				return ctrl.Result{}, err
+			}
+			logger.Info("Updated Secret", "name", secret.Name, "namespace", secret.Namespace)
+
+			// Name the keys, never the values
+			if len(drifted) > 0 {
+				logger.Info("Reverted out-of-band changes to Secret", "name", secret.Name, "namespace", secret.Namespace, "keys", drifted)
+				if r.Recorder != nil {
+					r.Recorder.Eventf(timSecret, corev1.EventTypeWarning, "DriftDetected",
+						"Reverted out-of-band changes to keys %s of Secret %s/%s", strings.Join(drifted, ", "), secret.Namespace, secret.Name)
+				}
+			}
+		} else {
+			logger.Info("Secret data unchanged, skipping update", "name", secret.Name, "namespace", secret.Namespace)
 		}
-		logger.Info("Updated Secret", "name", secret.Name, "namespace", secret.Namespace)
-	} else {
-		logger.Info("Secret data unchanged, skipping update", "name", secret.Name, "namespace", secret.Namespace)
-	}
 
-	// Restart deployment if secret changed and deployment name is specified
-	if secretChanged && timSecret.Spec.DeploymentName != "" {
-		if err := r.restartDeployment(ctx, timSecret.Spec.DeploymentName, namespace); err != nil {
-			logger.Error(err, "Failed to restart Deployment")
-			return ctrl.Result{}, err
+		// Restart deployment if secret changed and deployment name is specified
+		if secretChanged && timSecret.Spec.DeploymentName != "" {
+			if err := r.restartDeployment(ctx, timSecret.Spec.DeploymentName, namespace); err != nil {
+				logger.Error(err, "Failed to restart Deployment")
+				return ctrl.Result{}, err
+			}
+			logger.Info("Restarted Deployment", "name", timSecret.Spec.DeploymentName, "namespace", namespace)
 		}
-		logger.Info("Restarted Deployment", "name", timSecret.Spec.DeploymentName, "namespace", namespace)
 	}
 
 	// Update status - success, reset retry count
 	now := metav1.Now()
 	timSecret.Status.LastSyncTime = &now
 	timSecret.Status.SecretHash = newHash
+	timSecret.Status.Sources = sourceStatuses(sources)
 	timSecret.Status.RetryCount = 0 // Reset on success
 	timSecret.Status.LastError = "" // Clear error
-	timSecret.Status.Conditions = []metav1.Condition{
-		{
-			Type:               "Ready",
-			Status:             metav1.ConditionTrue,
-			LastTransitionTime: now,
-			Reason:             "SecretSynced",
-			Message:            "Secret successfully synced from Vault",
-		},
+	meta.SetStatusCondition(&timSecret.Status.Conditions, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+	if secret != nil {
+		setDriftCondition(&timSecret.Status, secret, drifted, now, syncInterval)
 	}
 
 	if err := r.Status().Update(ctx, timSecret); err != nil {
@@ -185,6 +386,45 @@ func (r *TimSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	return ctrl.Result{RequeueAfter: syncInterval}, nil
 }
 
+// checkMergeTarget reports why an existing Secret cannot be written with
+// creationPolicy Merge. The Secret belongs to someone else, so it is never
+// recreated to change its type or to lift immutable.
+func checkMergeTarget(secret *corev1.Secret, target *secretsv1alpha1.SecretTarget) error {
+	if target != nil && target.Type != "" && secret.Type != targetSecretType(target) {
+		return fmt.Errorf("Secret %s/%s is of type %s, but target.type is %s", secret.Namespace, secret.Name, secret.Type, targetSecretType(target))
+	}
+	if isImmutable(secret) {
+		return fmt.Errorf("Secret %s/%s is immutable; creationPolicy Merge cannot write into it", secret.Namespace, secret.Name)
+	}
+	return nil
+}
+
+// recreateSecret replaces a Secret whose type or immutability must change.
+// The old Secret is only deleted if it is unchanged since it was read, and is
+// restored if the replacement cannot be created, so workloads are never left
+// without their Secret.
+func (r *TimSecretReconciler) recreateSecret(ctx context.Context, old, replacement *corev1.Secret) error {
+	if err := r.Delete(ctx, old, client.Preconditions{UID: &old.UID, ResourceVersion: &old.ResourceVersion}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete Secret %s/%s to replace it: %w", old.Namespace, old.Name, err)
+	}
+
+	createErr := r.Create(ctx, replacement)
+	if createErr == nil {
+		return nil
+	}
+
+	restored := old.DeepCopy()
+	restored.ResourceVersion = ""
+	restored.UID = ""
+	restored.CreationTimestamp = metav1.Time{}
+	restored.DeletionTimestamp = nil
+	restored.ManagedFields = nil
+	if err := r.Create(ctx, restored); err != nil {
+		return fmt.Errorf("failed to create replacement Secret %s/%s: %w (restoring the old Secret failed: %v)", old.Namespace, old.Name, createErr, err)
+	}
+	return fmt.Errorf("failed to create replacement Secret %s/%s, restored the old Secret: %w", old.Namespace, old.Name, createErr)
+}
+
 // restartDeployment restarts a deployment by updating its annotation
 func (r *TimSecretReconciler) restartDeployment(ctx context.Context, name, namespace string) error {
 	deployment := &appsv1.Deployment{}
@@ -208,10 +448,50 @@ func (r *TimSecretReconciler) restartDeployment(ctx context.Context, name, names
 }
 
 // resolveVaultConfig resolves Vault configuration from TimSecretConfig or direct values
-func (r *TimSecretReconciler) resolveVaultConfig(ctx context.Context, ts *secretsv1alpha1.TimSecret) (string, string, error) {
+func (r *TimSecretReconciler) resolveVaultConfig(ctx context.Context, ts *secretsv1alpha1.TimSecret) (*vaultConfig, error) {
+	factory := r.vaultClientFactory()
+
 	// Priority: direct values > TimSecretConfig
-	if ts.Spec.VaultURL != "" && ts.Spec.VaultToken != "" {
-		return ts.Spec.VaultURL, ts.Spec.VaultToken, nil
+	if ts.Spec.VaultURL != "" && (ts.Spec.VaultToken != "" || ts.Spec.TokenSecretRef != nil) {
+		token, err := factory.resolveToken(ctx, ts.Spec.VaultToken, ts.Spec.TokenSecretRef, ts.Namespace, "TimSecret "+ts.Namespace+"/"+ts.Name)
+		if err != nil {
+			return nil, err
+		}
+		return &vaultConfig{url: ts.Spec.VaultURL, token: token, vaultNamespace: ts.Spec.VaultNamespace}, nil
+	}
+
+	if ref := ts.Spec.VaultConfigRef; ref != nil {
+		switch ref.Kind {
+		case "", "TimSecretConfig":
+			cfg, err := r.timSecretConfig(ctx, factory, ts.Namespace, ref.Name)
+			if err != nil {
+				return nil, err
+			}
+			return cfg.withVaultNamespace(ts.Spec.VaultNamespace).forTenant(ts.Namespace)
+
+		case "ClusterTimSecretConfig":
+			config := &secretsv1alpha1.ClusterTimSecretConfig{}
+			if err := r.Get(ctx, types.NamespacedName{Name: ref.Name}, config); err != nil {
+				return nil, fmt.Errorf("failed to get ClusterTimSecretConfig %s: %w", ref.Name, err)
+			}
+
+			allowed, err := namespaceAllowed(ctx, r.Client, config, ts.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				return nil, fmt.Errorf("namespace %s is not allowed to use ClusterTimSecretConfig %s", ts.Namespace, ref.Name)
+			}
+
+			cfg, err := factory.configFromClusterTimSecretConfig(ctx, config)
+			if err != nil {
+				return nil, err
+			}
+			return cfg.withVaultNamespace(ts.Spec.VaultNamespace).forTenant(ts.Namespace)
+
+		default:
+			return nil, fmt.Errorf("unsupported vaultConfigRef.kind %q, must be TimSecretConfig or ClusterTimSecretConfig", ref.Kind)
+		}
 	}
 
 	// Try to get from TimSecretConfig
@@ -220,17 +500,66 @@ func (r *TimSecretReconciler) resolveVaultConfig(ctx context.Context, ts *secret
 		if namespace == "" {
 			namespace = ts.Namespace
 		}
+		if err := checkConfigNamespace(ctx, r.Client, r.RejectCrossNamespaceConfig, ts.Namespace, namespace); err != nil {
+			return nil, err
+		}
 
-		config := &secretsv1alpha1.TimSecretConfig{}
-		err := r.Get(ctx, types.NamespacedName{Name: ts.Spec.VaultConfig, Namespace: namespace}, config)
+		cfg, err := r.timSecretConfig(ctx, factory, namespace, ts.Spec.VaultConfig)
 		if err != nil {
-			return "", "", fmt.Errorf("failed to get TimSecretConfig %s/%s: %w", namespace, ts.Spec.VaultConfig, err)
+			return nil, err
+		}
+		return cfg.withVaultNamespace(ts.Spec.VaultNamespace).forTenant(ts.Namespace)
+	}
+
+	return nil, fmt.Errorf("either vaultConfigRef, vaultConfig or vaultURL with tokenSecretRef (or vaultToken) must be specified")
+}
+
+// timSecretConfig resolves the Vault settings of a TimSecretConfig
+func (r *TimSecretReconciler) timSecretConfig(ctx context.Context, factory *vaultClientFactory, namespace, name string) (*vaultConfig, error) {
+	config := &secretsv1alpha1.TimSecretConfig{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, config); err != nil {
+		return nil, fmt.Errorf("failed to get TimSecretConfig %s/%s: %w", namespace, name, err)
+	}
+
+	return factory.configFromTimSecretConfig(ctx, config)
+}
+
+// namespaceAllowed reports whether TimSecrets in the namespace may use the
+// ClusterTimSecretConfig. Namespaces must be listed in allowedNamespaces or
+// match the namespaceSelector; a config with neither allows no namespace.
+func namespaceAllowed(ctx context.Context, c client.Reader, config *secretsv1alpha1.ClusterTimSecretConfig, namespace string) (bool, error) {
+	for _, allowed := range config.Spec.AllowedNamespaces {
+		if allowed == namespace {
+			return true, nil
 		}
+	}
+
+	if config.Spec.NamespaceSelector == nil {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(config.Spec.NamespaceSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid namespaceSelector on ClusterTimSecretConfig %s: %w", config.Name, err)
+	}
 
-		return config.Spec.VaultURL, config.Spec.VaultToken, nil
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return false, fmt.Errorf("failed to get Namespace %s: %w", namespace, err)
 	}
 
-	return "", "", fmt.Errorf("either vaultConfig or both vaultURL and vaultToken must be specified")
+	return selector.Matches(labels.Set(ns.Labels)), nil
+}
+
+// vaultClientFactory returns the factory used to build Vault clients
+func (r *TimSecretReconciler) vaultClientFactory() *vaultClientFactory {
+	return &vaultClientFactory{
+		Client:                   r.Client,
+		clients:                  r.VaultClients,
+		rejectInlineTokens:       r.RejectInlineTokens,
+		tokenAudiences:           r.ServiceAccountTokenAudiences,
+		clusterResourceNamespace: r.ClusterResourceNamespace,
+	}
 }
 
 // updateCondition updates a single condition in the TimSecret status
@@ -326,15 +655,12 @@ func (r *TimSecretReconciler) handleError(ctx context.Context, ts *secretsv1alph
 	}
 
 	// Update status with failure condition
-	ts.Status.Conditions = []metav1.Condition{
-		{
-			Type:               "Ready",
-			Status:             metav1.ConditionFalse,
-			LastTransitionTime: metav1.Now(),
-			Reason:             reason,
-			Message:            fmt.Sprintf("Retry %d (max %d): %v", ts.Status.RetryCount, maxRetries, err),
-		},
-	}
+	meta.SetStatusCondition(&ts.Status.Conditions, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: fmt.Sprintf("Retry %d (max %d): %v", ts.Status.RetryCount, maxRetries, err),
+	})
 
 	if updateErr := r.Status().Update(ctx, ts); updateErr != nil {
 		// If we can't update status, return both errors
@@ -352,9 +678,16 @@ func (r *TimSecretReconciler) handleError(ctx context.Context, ts *secretsv1alph
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *TimSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.VaultClients == nil {
+		r.VaultClients = vault.NewClientCache()
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&secretsv1alpha1.TimSecret{}).
-		Owns(&corev1.Secret{}).
+		// Managed Secrets may live in any namespace, so they are mapped back
+		// to their TimSecret through the ownership labels
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(secretToTimSecret),
+			builder.WithPredicates(managedSecretPredicate)).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: 10, // Process 10 TimSecrets in parallel
 		}).
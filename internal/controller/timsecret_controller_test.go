@@ -1,9 +1,34 @@
 package controller
 
 import (
+	"context"
+	"fmt"
 	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
 )
 
+// newFakeClientBuilder returns a fake client builder knowing the operator's and Kubernetes' types
+func newFakeClientBuilder(t *testing.T) *fake.ClientBuilder {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := secretsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme)
+}
+
 func TestCalculateHash_Deterministic(t *testing.T) {
 	data := map[string]string{
 		"password": "secret123",
@@ -84,3 +109,44 @@ func TestCalculateHash_EmptyData(t *testing.T) {
 		t.Error("Empty data produced different hashes")
 	}
 }
+
+func TestRecreateSecretRestoresOnFailure(t *testing.T) {
+	ctx := context.Background()
+	old := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-secrets", Namespace: "team-a"},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{"tls.crt": []byte("cert")},
+	}
+
+	// Only the replacement is refused, e.g. by quota or an admission webhook
+	c := newFakeClientBuilder(t).WithObjects(old).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if secret, ok := obj.(*corev1.Secret); ok && secret.Type == corev1.SecretTypeTLS {
+				return fmt.Errorf("exceeded quota")
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	}).Build()
+	r := &TimSecretReconciler{Client: c}
+
+	current := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "app-secrets", Namespace: "team-a"}, current); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	replacement := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-secrets", Namespace: "team-a"},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{"tls.crt": []byte("cert"), "tls.key": []byte("key")},
+	}
+	if err := r.recreateSecret(ctx, current, replacement); err == nil {
+		t.Fatal("expected an error when the replacement cannot be created")
+	}
+
+	restored := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "app-secrets", Namespace: "team-a"}, restored); err != nil {
+		t.Fatalf("expected the old Secret to be restored, got %v", err)
+	}
+	if restored.Type != corev1.SecretTypeOpaque || string(restored.Data["tls.crt"]) != "cert" {
+		t.Errorf("unexpected restored Secret %+v", restored)
+	}
+}
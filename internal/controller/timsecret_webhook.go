@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+// TimSecretWebhook defaults TimSecrets and rejects those the reconciler
+// would refuse to sync
+type TimSecretWebhook struct {
+	client.Client
+
+	// AllowCrossNamespaceTargets lets TimSecrets target any namespace
+	AllowCrossNamespaceTargets bool
+
+	// RejectInlineTokens refuses the deprecated inline vaultToken field
+	RejectInlineTokens bool
+
+	// RejectCrossNamespaceConfig refuses vaultConfigNamespace references to other namespaces
+	RejectCrossNamespaceConfig bool
+}
+
+// +kubebuilder:webhook:path=/mutate-secrets-tim-operator-v1alpha1-timsecret,mutating=true,failurePolicy=fail,sideEffects=None,groups=secrets.tim.operator,resources=timsecrets,verbs=create;update,versions=v1alpha1,name=mtimsecret.secrets.tim.operator,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-secrets-tim-operator-v1alpha1-timsecret,mutating=false,failurePolicy=fail,sideEffects=None,groups=secrets.tim.operator,resources=timsecrets,verbs=create;update,versions=v1alpha1,name=vtimsecret.secrets.tim.operator,admissionReviewVersions=v1
+
+var _ admission.CustomDefaulter = &TimSecretWebhook{}
+var _ admission.CustomValidator = &TimSecretWebhook{}
+
+// Default implements admission.CustomDefaulter
+func (w *TimSecretWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	ts, ok := obj.(*secretsv1alpha1.TimSecret)
+	if !ok {
+		return fmt.Errorf("expected a TimSecret, got %T", obj)
+	}
+
+	defaultTimSecret(&ts.Spec)
+	return nil
+}
+
+// ValidateCreate implements admission.CustomValidator
+func (w *TimSecretWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	ts, ok := obj.(*secretsv1alpha1.TimSecret)
+	if !ok {
+		return nil, fmt.Errorf("expected a TimSecret, got %T", obj)
+	}
+	return w.validate(ctx, ts, nil)
+}
+
+// ValidateUpdate implements admission.CustomValidator. Updates that leave the
+// spec alone, such as finalizer removal, are always allowed: the referenced
+// config or target namespace may be gone by then, and refusing them would
+// leave the TimSecret stuck in Terminating.
+func (w *TimSecretWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldTs, ok := oldObj.(*secretsv1alpha1.TimSecret)
+	if !ok {
+		return nil, fmt.Errorf("expected a TimSecret, got %T", oldObj)
+	}
+	ts, ok := newObj.(*secretsv1alpha1.TimSecret)
+	if !ok {
+		return nil, fmt.Errorf("expected a TimSecret, got %T", newObj)
+	}
+
+	if ts.DeletionTimestamp != nil || equality.Semantic.DeepEqual(oldTs.Spec, ts.Spec) {
+		return nil, nil
+	}
+	return w.validate(ctx, ts, oldTs)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (w *TimSecretWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks a TimSecret's spec, its configuration reference and the
+// operator's policies. On update, old is the previous TimSecret; the
+// referenced config and the target namespace are only looked up again when
+// they changed.
+func (w *TimSecretWebhook) validate(ctx context.Context, ts, old *secretsv1alpha1.TimSecret) (admission.Warnings, error) {
+	specPath := field.NewPath("spec")
+	errs, warnings := validateTimSecretSpec(&ts.Spec, specPath)
+
+	if w.RejectInlineTokens && ts.Spec.VaultToken != "" {
+		errs = append(errs, field.Forbidden(specPath.Child("vaultToken"), "inline Vault tokens are disabled, use tokenSecretRef"))
+	}
+	if ref := ts.Spec.TokenSecretRef; ref != nil {
+		if err := checkTokenSecretNamespace(ref, ts.Namespace); err != nil {
+			errs = append(errs, field.Forbidden(specPath.Child("tokenSecretRef", "namespace"), err.Error()))
+		}
+	}
+	if w.RejectCrossNamespaceConfig && ts.Spec.VaultConfigNamespace != "" && ts.Spec.VaultConfigNamespace != ts.Namespace {
+		errs = append(errs, field.Forbidden(specPath.Child("vaultConfigNamespace"), "cross-namespace TimSecretConfig references are disabled, use a ClusterTimSecretConfig"))
+	}
+
+	// Only look up the referenced configuration once the spec itself is valid
+	if len(errs) == 0 && (old == nil || configReferenceChanged(&old.Spec, &ts.Spec)) {
+		configErrs, err := w.validateConfigReference(ctx, ts, specPath)
+		if err != nil {
+			return warnings, err
+		}
+		errs = append(errs, configErrs...)
+	}
+
+	if old == nil || old.Spec.Namespace != ts.Spec.Namespace {
+		if err := checkTargetNamespace(ctx, w.Client, w.AllowCrossNamespaceTargets, ts.Namespace, ts.Spec.Namespace); err != nil {
+			errs = append(errs, field.Forbidden(specPath.Child("namespace"), err.Error()))
+		}
+	}
+
+	if len(errs) > 0 {
+		return warnings, apierrors.NewInvalid(secretsv1alpha1.GroupVersion.WithKind("TimSecret").GroupKind(), ts.Name, errs)
+	}
+	return warnings, nil
+}
+
+// configReferenceChanged reports whether an update points a TimSecret at another configuration
+func configReferenceChanged(old, spec *secretsv1alpha1.TimSecretSpec) bool {
+	return !equality.Semantic.DeepEqual(old.VaultConfigRef, spec.VaultConfigRef) ||
+		old.VaultConfig != spec.VaultConfig ||
+		old.VaultConfigNamespace != spec.VaultConfigNamespace
+}
+
+// validateConfigReference checks that the referenced configuration exists
+// and may be used from the TimSecret's namespace
+func (w *TimSecretWebhook) validateConfigReference(ctx context.Context, ts *secretsv1alpha1.TimSecret, specPath *field.Path) (field.ErrorList, error) {
+	var errs field.ErrorList
+
+	switch ref := ts.Spec.VaultConfigRef; {
+	case ref != nil && ref.Kind == "ClusterTimSecretConfig":
+		namePath := specPath.Child("vaultConfigRef", "name")
+		config := &secretsv1alpha1.ClusterTimSecretConfig{}
+		if err := w.Get(ctx, types.NamespacedName{Name: ref.Name}, config); err != nil {
+			if apierrors.IsNotFound(err) {
+				return append(errs, field.NotFound(namePath, ref.Name)), nil
+			}
+			return nil, err
+		}
+
+		allowed, err := namespaceAllowed(ctx, w.Client, config, ts.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			errs = append(errs, field.Forbidden(namePath, fmt.Sprintf("namespace %s is not allowed to use ClusterTimSecretConfig %s", ts.Namespace, ref.Name)))
+		}
+
+	case ref != nil:
+		return w.validateTimSecretConfigExists(ctx, ts.Namespace, ref.Name, specPath.Child("vaultConfigRef", "name"))
+
+	case ts.Spec.VaultConfig != "":
+		namespace := ts.Spec.VaultConfigNamespace
+		if namespace == "" {
+			namespace = ts.Namespace
+		}
+		if err := checkConfigNamespace(ctx, w.Client, w.RejectCrossNamespaceConfig, ts.Namespace, namespace); err != nil {
+			return append(errs, field.Forbidden(specPath.Child("vaultConfigNamespace"), err.Error())), nil
+		}
+		return w.validateTimSecretConfigExists(ctx, namespace, ts.Spec.VaultConfig, specPath.Child("vaultConfig"))
+	}
+
+	return errs, nil
+}
+
+// validateTimSecretConfigExists reports a field error if the TimSecretConfig cannot be found
+func (w *TimSecretWebhook) validateTimSecretConfigExists(ctx context.Context, namespace, name string, path *field.Path) (field.ErrorList, error) {
+	config := &secretsv1alpha1.TimSecretConfig{}
+	if err := w.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, config); err != nil {
+		if apierrors.IsNotFound(err) {
+			return field.ErrorList{field.NotFound(path, namespace+"/"+name)}, nil
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+// SetupWebhookWithManager registers the webhooks with the Manager.
+func (w *TimSecretWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&secretsv1alpha1.TimSecret{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
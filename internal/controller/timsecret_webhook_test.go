@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+func TestTimSecretWebhookValidateUpdate(t *testing.T) {
+	ctx := context.Background()
+	// The referenced TimSecretConfig does not exist (any more)
+	w := &TimSecretWebhook{Client: newFakeClientBuilder(t).Build()}
+
+	ts := &secretsv1alpha1.TimSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a", Finalizers: []string{TimSecretFinalizer}},
+		Spec: secretsv1alpha1.TimSecretSpec{
+			VaultConfigRef: &secretsv1alpha1.VaultConfigReference{Kind: "TimSecretConfig", Name: "deleted"},
+			VaultPath:      "secret/data/app",
+			SecretName:     "app-secrets",
+		},
+	}
+
+	if _, err := w.ValidateCreate(ctx, ts); err == nil {
+		t.Error("expected creation to fail without the referenced config")
+	}
+
+	// Removing the finalizer of a deleted TimSecret must not be blocked
+	now := metav1.Now()
+	deleting := ts.DeepCopy()
+	deleting.DeletionTimestamp = &now
+	finalized := deleting.DeepCopy()
+	finalized.Finalizers = nil
+	if _, err := w.ValidateUpdate(ctx, deleting, finalized); err != nil {
+		t.Errorf("unexpected error removing the finalizer: %v", err)
+	}
+
+	// Neither must metadata-only updates
+	labeled := ts.DeepCopy()
+	labeled.Labels = map[string]string{"team": "a"}
+	if _, err := w.ValidateUpdate(ctx, ts, labeled); err != nil {
+		t.Errorf("unexpected error for a metadata-only update: %v", err)
+	}
+
+	// Spec changes keeping the reference are checked without looking it up
+	resynced := ts.DeepCopy()
+	resynced.Spec.SyncInterval = "10m"
+	if _, err := w.ValidateUpdate(ctx, ts, resynced); err != nil {
+		t.Errorf("unexpected error for an update keeping the config reference: %v", err)
+	}
+	resynced.Spec.SyncInterval = "10 minutes"
+	if _, err := w.ValidateUpdate(ctx, ts, resynced); err == nil {
+		t.Error("expected an error for an invalid spec")
+	}
+
+	moved := ts.DeepCopy()
+	moved.Spec.VaultConfigRef.Name = "other"
+	if _, err := w.ValidateUpdate(ctx, ts, moved); err == nil {
+		t.Error("expected an error for a reference to a missing config")
+	}
+}
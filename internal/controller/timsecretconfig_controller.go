@@ -0,0 +1,288 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+	"github.com/renatoruis/timvault-operator/internal/vault"
+)
+
+// TimSecretConfigReconciler manages the Vault token of each TimSecretConfig:
+// it looks the token up, renews it before it expires, re-authenticates when
+// renewal is not possible and reports the token state in the status
+type TimSecretConfigReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// VaultClients caches authenticated Vault clients per TimSecretConfig.
+	// Must be shared with the TimSecretReconciler so both use the same tokens.
+	VaultClients *vault.ClientCache
+
+	// RejectInlineTokens refuses the deprecated inline vaultToken fields
+	RejectInlineTokens bool
+
+	// ServiceAccountTokenAudiences are the ServiceAccount token audiences
+	// TimSecretConfigs may request, empty allows only "vault"
+	ServiceAccountTokenAudiences []string
+}
+
+// +kubebuilder:rbac:groups=secrets.tim.operator,resources=timsecretconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=secrets.tim.operator,resources=timsecretconfigs/status,verbs=get;update;patch
+
+// Reconcile checks the Vault token of a TimSecretConfig and keeps it alive
+func (r *TimSecretConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	// Fetch the TimSecretConfig instance
+	config := &secretsv1alpha1.TimSecretConfig{}
+	err := r.Get(ctx, req.NamespacedName, config)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("TimSecretConfig resource not found. Ignoring since object must be deleted")
+			// Drop the cached clients and their tokens
+			if r.VaultClients != nil {
+				r.VaultClients.Remove(timSecretConfigCacheID(req.Namespace, req.Name))
+			}
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get TimSecretConfig")
+		return ctrl.Result{}, err
+	}
+
+	factory := r.vaultClientFactory()
+	return reconcileToken(ctx, r.Client, factory, config, &config.Spec, &config.Status, func() (*vaultConfig, error) {
+		return factory.configFromTimSecretConfig(ctx, config)
+	})
+}
+
+// reconcileToken checks the Vault token of a TimSecretConfig or
+// ClusterTimSecretConfig, keeps it alive and records its state in status
+func reconcileToken(ctx context.Context, c client.Client, factory *vaultClientFactory, obj client.Object, spec *secretsv1alpha1.TimSecretConfigSpec, status *secretsv1alpha1.TimSecretConfigStatus, resolve func() (*vaultConfig, error)) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	// With a role template every TimSecret namespace logs in with its own
+	// role; the tokens cached for those namespaces are kept alive here
+	if spec.Auth != nil && spec.Auth.RoleTemplate != "" {
+		return renewTenantTokens(ctx, c, factory, obj, status, resolve)
+	}
+
+	vaultCfg, err := resolve()
+	if err != nil {
+		logger.Error(err, "Failed to resolve Vault configuration")
+		return handleTokenError(ctx, c, obj, status, nil, err, "VaultConfigResolutionFailed")
+	}
+
+	vaultClient, err := factory.newVaultClient(vaultCfg)
+	if err != nil {
+		logger.Error(err, "Failed to create Vault client")
+		return handleTokenError(ctx, c, obj, status, nil, err, "VaultClientCreationFailed")
+	}
+
+	info, err := vaultClient.ManageToken(ctx)
+	if err != nil {
+		logger.Error(err, "Failed to manage Vault token")
+		return handleTokenError(ctx, c, obj, status, info, err, "TokenRenewalFailed")
+	}
+
+	now := metav1.Now()
+	setTokenInfo(status, info)
+	status.LastCheckTime = &now
+	status.RenewalFailures = 0
+	status.LastError = ""
+
+	reason, message := "TokenValid", "Vault token is valid"
+	switch {
+	case info.Reauthenticated:
+		status.LastRenewalTime = &now
+		reason, message = "TokenReauthenticated", "Obtained a new Vault token from the auth method"
+		logger.Info("Re-authenticated to Vault", "ttl", info.TTL)
+	case info.Renewed:
+		status.LastRenewalTime = &now
+		reason, message = "TokenRenewed", "Vault token renewed"
+		logger.Info("Renewed Vault token", "ttl", info.TTL)
+	}
+
+	status.Conditions = []metav1.Condition{
+		{
+			Type:               "Ready",
+			Status:             metav1.ConditionTrue,
+			LastTransitionTime: now,
+			Reason:             reason,
+			Message:            message,
+		},
+	}
+
+	if err := c.Status().Update(ctx, obj); err != nil {
+		logger.Error(err, "Failed to update Vault configuration status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: tokenCheckInterval(info.TTL)}, nil
+}
+
+// renewTenantTokens keeps the tokens cached per TimSecret namespace alive and
+// checks them again before the first one expires. A namespace whose token
+// could not be renewed logs in again on its next sync.
+func renewTenantTokens(ctx context.Context, c client.Client, factory *vaultClientFactory, obj client.Object, status *secretsv1alpha1.TimSecretConfigStatus, resolve func() (*vaultConfig, error)) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	vaultCfg, err := resolve()
+	if err != nil {
+		logger.Error(err, "Failed to resolve Vault configuration")
+		return handleTokenError(ctx, c, obj, status, nil, err, "VaultConfigResolutionFailed")
+	}
+
+	var tenantClients []*vault.Client
+	if factory.clients != nil {
+		tenantClients = factory.clients.Clients(vaultCfg.cacheID, vaultCfg.cacheVersion)
+	}
+
+	interval := tokenCheckInterval(0)
+	var lastErr error
+	failures := 0
+	for _, vaultClient := range tenantClients {
+		info, err := vaultClient.ManageToken(ctx)
+		if err != nil {
+			logger.Error(err, "Failed to manage per-tenant Vault token")
+			lastErr = err
+			failures++
+			continue
+		}
+		if next := tokenCheckInterval(info.TTL); next < interval {
+			interval = next
+		}
+	}
+
+	return setPerTenantStatus(ctx, c, obj, status, len(tenantClients), failures, lastErr, interval)
+}
+
+// setPerTenantStatus reports that tokens are managed per TimSecret namespace
+func setPerTenantStatus(ctx context.Context, c client.Client, obj client.Object, status *secretsv1alpha1.TimSecretConfigStatus, tenants, failures int, lastErr error, interval time.Duration) (ctrl.Result, error) {
+	now := metav1.Now()
+	message := fmt.Sprintf("Vault tokens are obtained per TimSecret namespace using auth.roleTemplate, %d cached", tenants)
+	if failures > 0 {
+		message = fmt.Sprintf("%d of %d per-namespace Vault tokens could not be renewed; those namespaces log in again on their next sync", failures, tenants)
+	}
+
+	*status = secretsv1alpha1.TimSecretConfigStatus{
+		LastCheckTime: &now,
+		Conditions: []metav1.Condition{
+			{
+				Type:               "Ready",
+				Status:             metav1.ConditionTrue,
+				LastTransitionTime: now,
+				Reason:             "PerTenantTokens",
+				Message:            message,
+			},
+		},
+	}
+	if lastErr != nil {
+		status.LastError = lastErr.Error()
+	}
+
+	if err := c.Status().Update(ctx, obj); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to update Vault configuration status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// handleTokenError records a failed token check and retries with exponential backoff
+func handleTokenError(ctx context.Context, c client.Client, obj client.Object, status *secretsv1alpha1.TimSecretConfigStatus, info *vault.TokenInfo, err error, reason string) (ctrl.Result, error) {
+	now := metav1.Now()
+	if info != nil {
+		setTokenInfo(status, info)
+	}
+	status.LastCheckTime = &now
+	status.RenewalFailures++
+	status.LastError = err.Error()
+
+	// 10s, 20s, 40s, ... capped at 5 minutes
+	exponent := status.RenewalFailures - 1
+	if exponent > 5 {
+		exponent = 5
+	}
+	backoff := 10 * time.Second * time.Duration(1<<uint(exponent))
+	if backoff > 5*time.Minute {
+		backoff = 5 * time.Minute
+	}
+
+	status.Conditions = []metav1.Condition{
+		{
+			Type:               "Ready",
+			Status:             metav1.ConditionFalse,
+			LastTransitionTime: now,
+			Reason:             reason,
+			Message:            fmt.Sprintf("Failure %d: %v", status.RenewalFailures, err),
+		},
+	}
+
+	if updateErr := c.Status().Update(ctx, obj); updateErr != nil {
+		return ctrl.Result{RequeueAfter: backoff}, fmt.Errorf("failed to update status: %w (original error: %v)", updateErr, err)
+	}
+
+	return ctrl.Result{RequeueAfter: backoff}, nil
+}
+
+// setTokenInfo copies the token state into the TimSecretConfig status
+func setTokenInfo(status *secretsv1alpha1.TimSecretConfigStatus, info *vault.TokenInfo) {
+	status.TokenRenewable = info.Renewable
+	status.TokenTTL = ""
+	status.TokenExpireTime = nil
+	if info.TTL > 0 {
+		status.TokenTTL = info.TTL.Round(time.Second).String()
+		expireTime := metav1.NewTime(info.ExpireTime)
+		status.TokenExpireTime = &expireTime
+	}
+}
+
+// tokenCheckInterval returns how long to wait before checking a token again:
+// a quarter of its remaining TTL, between 10 seconds and 1 hour
+func tokenCheckInterval(ttl time.Duration) time.Duration {
+	if ttl == 0 {
+		return 1 * time.Hour
+	}
+
+	interval := ttl / 4
+	if interval < 10*time.Second {
+		return 10 * time.Second
+	}
+	if interval > 1*time.Hour {
+		return 1 * time.Hour
+	}
+	return interval
+}
+
+// vaultClientFactory returns the factory used to build Vault clients
+func (r *TimSecretConfigReconciler) vaultClientFactory() *vaultClientFactory {
+	return &vaultClientFactory{
+		Client:             r.Client,
+		clients:            r.VaultClients,
+		rejectInlineTokens: r.RejectInlineTokens,
+		tokenAudiences:     r.ServiceAccountTokenAudiences,
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TimSecretConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.VaultClients == nil {
+		r.VaultClients = vault.NewClientCache()
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		// Status updates must not trigger a new token check
+		For(&secretsv1alpha1.TimSecretConfig{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
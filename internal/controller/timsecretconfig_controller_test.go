@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+	"github.com/renatoruis/timvault-operator/internal/vault"
+)
+
+func TestTokenCheckInterval(t *testing.T) {
+	tests := []struct {
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{ttl: 0, want: 1 * time.Hour},                   // Non-expiring token
+		{ttl: 20 * time.Second, want: 10 * time.Second}, // Minimum interval
+		{ttl: 1 * time.Hour, want: 15 * time.Minute},
+		{ttl: 768 * time.Hour, want: 1 * time.Hour}, // Maximum interval
+	}
+
+	for _, tt := range tests {
+		if got := tokenCheckInterval(tt.ttl); got != tt.want {
+			t.Errorf("tokenCheckInterval(%s) = %s, want %s", tt.ttl, got, tt.want)
+		}
+	}
+}
+
+func TestRenewTenantTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/token/lookup-self" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"ttl": 400, "creation_ttl": 600, "renewable": true}}`))
+	}))
+	defer server.Close()
+
+	config := &secretsv1alpha1.TimSecretConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "vault", Namespace: "vault-system"},
+		Spec:       secretsv1alpha1.TimSecretConfigSpec{Auth: &secretsv1alpha1.VaultAuth{RoleTemplate: "ns-{{ .Namespace }}"}},
+	}
+	c := newFakeClientBuilder(t).WithObjects(config).WithStatusSubresource(config).Build()
+	factory := &vaultClientFactory{Client: c, clients: vault.NewClientCache()}
+	resolve := func() (*vaultConfig, error) {
+		return &vaultConfig{cacheID: "TimSecretConfig/vault-system/vault", cacheVersion: "1"}, nil
+	}
+
+	// Without cached tenants the tokens are checked again after an hour
+	result, err := reconcileToken(context.Background(), c, factory, config, &config.Spec, &config.Status, resolve)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter != time.Hour {
+		t.Errorf("expected a requeue after an hour, got %s", result.RequeueAfter)
+	}
+
+	// Cached tenant tokens are checked before they expire
+	if _, err := factory.clients.Get("TimSecretConfig/vault-system/vault", "#ns-team-a", "1", func() (*vault.Client, error) {
+		return vault.NewClient(vault.Config{Address: server.URL}, "token")
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err = reconcileToken(context.Background(), c, factory, config, &config.Spec, &config.Status, resolve)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter != 100*time.Second {
+		t.Errorf("expected a requeue after a quarter of the token TTL, got %s", result.RequeueAfter)
+	}
+	if config.Status.LastError != "" || config.Status.Conditions[0].Reason != "PerTenantTokens" {
+		t.Errorf("unexpected status %+v", config.Status)
+	}
+}
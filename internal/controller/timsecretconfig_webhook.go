@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+// TimSecretConfigWebhook defaults and validates TimSecretConfigs and ClusterTimSecretConfigs
+type TimSecretConfigWebhook struct {
+	// RejectInlineTokens refuses the deprecated inline vaultToken field
+	RejectInlineTokens bool
+
+	// ServiceAccountTokenAudiences are the ServiceAccount token audiences
+	// TimSecretConfigs may request, empty allows only "vault"
+	ServiceAccountTokenAudiences []string
+
+	// ClusterResourceNamespace is where Secrets referenced by
+	// ClusterTimSecretConfigs are looked up
+	ClusterResourceNamespace string
+}
+
+// +kubebuilder:webhook:path=/mutate-secrets-tim-operator-v1alpha1-timsecretconfig,mutating=true,failurePolicy=fail,sideEffects=None,groups=secrets.tim.operator,resources=timsecretconfigs,verbs=create;update,versions=v1alpha1,name=mtimsecretconfig.secrets.tim.operator,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-secrets-tim-operator-v1alpha1-timsecretconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=secrets.tim.operator,resources=timsecretconfigs,verbs=create;update,versions=v1alpha1,name=vtimsecretconfig.secrets.tim.operator,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-secrets-tim-operator-v1alpha1-clustertimsecretconfig,mutating=true,failurePolicy=fail,sideEffects=None,groups=secrets.tim.operator,resources=clustertimsecretconfigs,verbs=create;update,versions=v1alpha1,name=mclustertimsecretconfig.secrets.tim.operator,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-secrets-tim-operator-v1alpha1-clustertimsecretconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=secrets.tim.operator,resources=clustertimsecretconfigs,verbs=create;update,versions=v1alpha1,name=vclustertimsecretconfig.secrets.tim.operator,admissionReviewVersions=v1
+
+var _ admission.CustomDefaulter = &TimSecretConfigWebhook{}
+var _ admission.CustomValidator = &TimSecretConfigWebhook{}
+
+// Default implements admission.CustomDefaulter
+func (w *TimSecretConfigWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	switch config := obj.(type) {
+	case *secretsv1alpha1.TimSecretConfig:
+		defaultTimSecretConfigSpec(&config.Spec)
+	case *secretsv1alpha1.ClusterTimSecretConfig:
+		defaultTimSecretConfigSpec(&config.Spec.TimSecretConfigSpec)
+	default:
+		return fmt.Errorf("expected a TimSecretConfig or ClusterTimSecretConfig, got %T", obj)
+	}
+	return nil
+}
+
+// ValidateCreate implements admission.CustomValidator
+func (w *TimSecretConfigWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return w.validate(obj)
+}
+
+// ValidateUpdate implements admission.CustomValidator
+func (w *TimSecretConfigWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	return w.validate(newObj)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (w *TimSecretConfigWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the spec of a TimSecretConfig or ClusterTimSecretConfig
+func (w *TimSecretConfigWebhook) validate(obj runtime.Object) (admission.Warnings, error) {
+	specPath := field.NewPath("spec")
+
+	var errs field.ErrorList
+	var warnings []string
+	var spec *secretsv1alpha1.TimSecretConfigSpec
+	var kind, name, secretNamespace string
+
+	switch config := obj.(type) {
+	case *secretsv1alpha1.TimSecretConfig:
+		errs, warnings = validateTimSecretConfigSpec(&config.Spec, false, specPath)
+		spec, kind, name = &config.Spec, "TimSecretConfig", config.Name
+		secretNamespace = config.Namespace
+	case *secretsv1alpha1.ClusterTimSecretConfig:
+		errs, warnings = validateClusterTimSecretConfigSpec(&config.Spec, specPath)
+		spec, kind, name = &config.Spec.TimSecretConfigSpec, "ClusterTimSecretConfig", config.Name
+		secretNamespace = w.ClusterResourceNamespace
+	default:
+		return nil, fmt.Errorf("expected a TimSecretConfig or ClusterTimSecretConfig, got %T", obj)
+	}
+
+	if w.RejectInlineTokens && spec.VaultToken != "" {
+		errs = append(errs, field.Forbidden(specPath.Child("vaultToken"), "inline Vault tokens are disabled, use tokenSecretRef"))
+	}
+
+	// Namespaced configs must not mint tokens the kube-apiserver accepts
+	if _, ok := obj.(*secretsv1alpha1.TimSecretConfig); ok && spec.Auth != nil {
+		if k8s := spec.Auth.Kubernetes; k8s != nil && k8s.Audience != "" {
+			if err := checkServiceAccountTokenAudience(k8s.Audience, w.ServiceAccountTokenAudiences); err != nil {
+				errs = append(errs, field.Forbidden(specPath.Child("auth", "kubernetes", "audience"), err.Error()))
+			}
+		}
+		if jwt := spec.Auth.JWT; jwt != nil && jwt.Audience != "" {
+			if err := checkServiceAccountTokenAudience(jwt.Audience, w.ServiceAccountTokenAudiences); err != nil {
+				errs = append(errs, field.Forbidden(specPath.Child("auth", "jwt", "audience"), err.Error()))
+			}
+		}
+	}
+
+	if spec.TokenSecretRef != nil {
+		if err := checkTokenSecretNamespace(spec.TokenSecretRef, secretNamespace); err != nil {
+			errs = append(errs, field.Forbidden(specPath.Child("tokenSecretRef", "namespace"), err.Error()))
+		}
+	}
+
+	if len(errs) > 0 {
+		return warnings, apierrors.NewInvalid(secretsv1alpha1.GroupVersion.WithKind(kind).GroupKind(), name, errs)
+	}
+	return warnings, nil
+}
+
+// SetupWebhookWithManager registers the webhooks of both configuration kinds with the Manager.
+func (w *TimSecretConfigWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&secretsv1alpha1.TimSecretConfig{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete(); err != nil {
+		return err
+	}
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&secretsv1alpha1.ClusterTimSecretConfig{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+func TestTimSecretConfigWebhookAudience(t *testing.T) {
+	w := &TimSecretConfigWebhook{ClusterResourceNamespace: "timvault-operator-system"}
+	auth := &secretsv1alpha1.VaultAuth{
+		Kubernetes: &secretsv1alpha1.KubernetesAuth{Role: "app", ServiceAccountName: "vault-auth", Audience: "vault"},
+	}
+	spec := secretsv1alpha1.TimSecretConfigSpec{VaultURL: "https://vault:8200", Auth: auth}
+	config := &secretsv1alpha1.TimSecretConfig{ObjectMeta: metav1.ObjectMeta{Name: "vault", Namespace: "team-a"}, Spec: spec}
+
+	if _, err := w.ValidateCreate(context.Background(), config); err != nil {
+		t.Errorf("unexpected error for the default audience: %v", err)
+	}
+
+	// A namespaced config must not request tokens for the kube-apiserver
+	auth.Kubernetes.Audience = "https://kubernetes.default.svc.cluster.local"
+	if _, err := w.ValidateCreate(context.Background(), config); err == nil {
+		t.Error("expected an error for a kube-apiserver audience")
+	}
+
+	auth.Kubernetes = nil
+	auth.JWT = &secretsv1alpha1.JWTAuth{Role: "app", ServiceAccountName: "vault-auth", Audience: "https://kubernetes.default.svc.cluster.local"}
+	if _, err := w.ValidateCreate(context.Background(), config); err == nil {
+		t.Error("expected an error for a kube-apiserver jwt audience")
+	}
+
+	// Cluster administrators may pick any audience
+	cluster := &secretsv1alpha1.ClusterTimSecretConfig{ObjectMeta: metav1.ObjectMeta{Name: "vault"}}
+	cluster.Spec.TimSecretConfigSpec = spec
+	cluster.Spec.AllowedNamespaces = []string{"team-a"}
+	if _, err := w.ValidateCreate(context.Background(), cluster); err != nil {
+		t.Errorf("unexpected error for a ClusterTimSecretConfig: %v", err)
+	}
+}
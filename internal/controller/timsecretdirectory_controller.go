@@ -0,0 +1,383 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+	"github.com/renatoruis/timvault-operator/internal/vault"
+)
+
+// DirectoryLabel names the TimSecretDirectory that generated a TimSecret
+const DirectoryLabel = "secrets.tim.operator/directory"
+
+// defaultDirectorySecretName names one Secret per secret after the directory and the secret's path
+const defaultDirectorySecretName = `{{ .Directory }}-{{ .Path | replace "/" "-" | replace "_" "-" | lower }}`
+
+// directoryRetryInterval is how long a failed TimSecretDirectory sync waits before the next attempt
+const directoryRetryInterval = 30 * time.Second
+
+// TimSecretDirectoryReconciler lists a Vault path prefix and keeps a TimSecret
+// for every secret below it, or one TimSecret merging all of them. The
+// generated TimSecrets are owned by the directory and sync like any other.
+type TimSecretDirectoryReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// VaultClients caches authenticated Vault clients per TimSecretConfig
+	VaultClients *vault.ClientCache
+
+	// RejectInlineTokens refuses the deprecated inline vaultToken fields
+	RejectInlineTokens bool
+
+	// ServiceAccountTokenAudiences are the ServiceAccount token audiences
+	// TimSecretConfigs may request, empty allows only "vault"
+	ServiceAccountTokenAudiences []string
+
+	// RejectCrossNamespaceConfig refuses vaultConfigNamespace references to
+	// other namespaces
+	RejectCrossNamespaceConfig bool
+
+	// ClusterResourceNamespace is where Secrets, ConfigMaps and ServiceAccounts
+	// referenced by ClusterTimSecretConfigs are looked up
+	ClusterResourceNamespace string
+
+	// AllowCrossNamespaceTargets lets TimSecrets write Secrets and restart
+	// Deployments in any namespace
+	AllowCrossNamespaceTargets bool
+}
+
+// directoryNameData is the data the secretName template of a TimSecretDirectory is rendered with
+type directoryNameData struct {
+	Directory string
+	Path      string
+	Name      string
+}
+
+// +kubebuilder:rbac:groups=secrets.tim.operator,resources=timsecretdirectories,verbs=get;list;watch
+// +kubebuilder:rbac:groups=secrets.tim.operator,resources=timsecretdirectories/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=secrets.tim.operator,resources=timsecretdirectories/finalizers,verbs=update
+
+// Reconcile lists the Vault path of a TimSecretDirectory and creates, updates
+// and deletes its TimSecrets to match
+func (r *TimSecretDirectoryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	dir := &secretsv1alpha1.TimSecretDirectory{}
+	if err := r.Get(ctx, req.NamespacedName, dir); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("TimSecretDirectory resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get TimSecretDirectory")
+		return ctrl.Result{}, err
+	}
+
+	// Generated TimSecrets are deleted by the garbage collector, each
+	// applying its deletionPolicy to its Secret
+	if !dir.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	spec := dir.Spec.DeepCopy()
+	defaultTimSecretDirectory(spec)
+	secrets := r.timSecretReconciler()
+	syncInterval := secrets.parseSyncInterval(spec.SyncInterval)
+
+	if errs, _ := validateTimSecretDirectorySpec(spec, field.NewPath("spec")); len(errs) > 0 {
+		err := errs.ToAggregate()
+		logger.Error(err, "Invalid TimSecretDirectory spec")
+		return r.handleError(ctx, dir, err, "InvalidSpec")
+	}
+
+	// Vault settings are resolved exactly as for a TimSecret in the same namespace
+	vaultCfg, err := secrets.resolveVaultConfig(ctx, &secretsv1alpha1.TimSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: dir.Name, Namespace: dir.Namespace},
+		Spec:       secretsv1alpha1.TimSecretSpec{VaultConfigRef: spec.VaultConfigRef, VaultNamespace: spec.VaultNamespace},
+	})
+	if err != nil {
+		logger.Error(err, "Failed to resolve Vault configuration")
+		return r.handleError(ctx, dir, err, "VaultConfigResolutionFailed")
+	}
+
+	// A prefix no rule can allow never reaches Vault or the mount cache
+	if err := vaultCfg.checkListPathBeforeLookup(dir.Namespace, spec.VaultPath); err != nil {
+		logger.Error(err, "Vault path not allowed")
+		return r.handleError(ctx, dir, err, "PathNotAllowed")
+	}
+
+	vaultClient, err := secrets.vaultClientFactory().newVaultClient(vaultCfg)
+	if err != nil {
+		logger.Error(err, "Failed to create Vault client")
+		return r.handleError(ctx, dir, err, "VaultClientCreationFailed")
+	}
+
+	// The prefix is checked before listing so that the names of secrets below a
+	// forbidden path never show up in generated TimSecrets or errors
+	mount, err := vaultClient.LookupMount(ctx, spec.VaultPath)
+	if err != nil {
+		logger.Error(err, "Failed to look up the mount of the Vault path")
+		return r.handleError(ctx, dir, err, "MountLookupFailed")
+	}
+	if err := vaultCfg.checkListPath(dir.Namespace, spec.VaultPath, mount); err != nil {
+		logger.Error(err, "Vault path not allowed")
+		return r.handleError(ctx, dir, err, "PathNotAllowed")
+	}
+
+	leaves, err := vaultClient.ListSecrets(ctx, spec.VaultPath, spec.MaxSecrets)
+	if err != nil {
+		logger.Error(err, "Failed to list secrets in Vault")
+		reason := "VaultListFailed"
+		switch {
+		case errors.Is(err, vault.ErrTooManySecrets):
+			reason = "TooManySecrets"
+		case errors.Is(err, vault.ErrMountLookup):
+			reason = "MountLookupFailed"
+		}
+		return r.handleError(ctx, dir, err, reason)
+	}
+
+	paths := make([]string, len(leaves))
+	for i, leaf := range leaves {
+		paths[i] = path.Join(spec.VaultPath, leaf)
+		if err := vaultCfg.checkPath(dir.Namespace, paths[i], mount); err != nil {
+			logger.Error(err, "Vault path not allowed")
+			return r.handleError(ctx, dir, err, "PathNotAllowed")
+		}
+	}
+
+	desired, err := directoryTimSecrets(dir.Name, spec, leaves, paths)
+	if err != nil {
+		logger.Error(err, "Failed to generate TimSecrets")
+		reason := "InvalidSecretName"
+		if errors.Is(err, errEmptyDirectory) {
+			reason = "NoSecretsFound"
+		}
+		return r.handleError(ctx, dir, err, reason)
+	}
+
+	for _, ts := range desired {
+		if err := r.applyTimSecret(ctx, dir, ts); err != nil {
+			logger.Error(err, "Failed to apply TimSecret", "timsecret", ts.Name)
+			return r.handleError(ctx, dir, err, "TimSecretApplyFailed")
+		}
+	}
+
+	pruned, err := r.pruneTimSecrets(ctx, dir, desired)
+	if err != nil {
+		logger.Error(err, "Failed to delete TimSecrets")
+		return r.handleError(ctx, dir, err, "PruneFailed")
+	}
+	if pruned > 0 {
+		logger.Info("Deleted TimSecrets of secrets removed from Vault", "count", pruned)
+	}
+
+	now := metav1.Now()
+	dir.Status.LastSyncTime = &now
+	dir.Status.SecretCount = len(leaves)
+	dir.Status.LastError = ""
+	meta.SetStatusCondition(&dir.Status.Conditions, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionTrue,
+		Reason:  "Synced",
+		Message: fmt.Sprintf("%d secrets below %s managed by %d TimSecrets", len(leaves), spec.VaultPath, len(desired)),
+	})
+	if err := r.Status().Update(ctx, dir); err != nil {
+		logger.Error(err, "Failed to update TimSecretDirectory status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: syncInterval}, nil
+}
+
+// errEmptyDirectory is returned when no secrets are found below a directory's path
+var errEmptyDirectory = errors.New("no secrets found")
+
+// directoryTimSecrets returns the TimSecrets a directory should have for the
+// secrets found below its path: one per secret, or one with all as sources
+func directoryTimSecrets(dirName string, spec *secretsv1alpha1.TimSecretDirectorySpec, leaves, paths []string) ([]*secretsv1alpha1.TimSecret, error) {
+	// An empty listing is more likely a mistyped or temporarily missing prefix,
+	// or a policy hiding the secrets, than every secret being deleted; pruning
+	// on it would delete every generated TimSecret and, with them, the Secrets
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%w below %s", errEmptyDirectory, spec.VaultPath)
+	}
+
+	if spec.Mode == secretsv1alpha1.DirectoryModeMerge {
+		return []*secretsv1alpha1.TimSecret{newDirectoryTimSecret(spec, spec.SecretName, paths)}, nil
+	}
+
+	tmpl, err := parseDirectorySecretName(spec.SecretName)
+	if err != nil {
+		return nil, err
+	}
+
+	timSecrets := make([]*secretsv1alpha1.TimSecret, 0, len(leaves))
+	seen := map[string]string{}
+	for i, leaf := range leaves {
+		name, err := renderDirectorySecretName(tmpl, dirName, leaf)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := seen[name]; ok {
+			return nil, fmt.Errorf("secrets %s and %s both render the Secret name %q", other, leaf, name)
+		}
+		seen[name] = leaf
+		timSecrets = append(timSecrets, newDirectoryTimSecret(spec, name, paths[i:i+1]))
+	}
+	return timSecrets, nil
+}
+
+// newDirectoryTimSecret returns a TimSecret reading the paths into a Secret,
+// both named name. The first path is the vaultPath, the others are sources.
+func newDirectoryTimSecret(spec *secretsv1alpha1.TimSecretDirectorySpec, name string, paths []string) *secretsv1alpha1.TimSecret {
+	ts := &secretsv1alpha1.TimSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: secretsv1alpha1.TimSecretSpec{
+			VaultConfigRef: spec.VaultConfigRef.DeepCopy(),
+			VaultNamespace: spec.VaultNamespace,
+			VaultPath:      paths[0],
+			SecretName:     name,
+			Target:         spec.Target.DeepCopy(),
+			SyncInterval:   spec.SyncInterval,
+			DeletionPolicy: spec.DeletionPolicy,
+			CreationPolicy: spec.CreationPolicy,
+			ConflictPolicy: spec.ConflictPolicy,
+		},
+	}
+	for _, p := range paths[1:] {
+		ts.Spec.Sources = append(ts.Spec.Sources, secretsv1alpha1.VaultSource{VaultPath: p})
+	}
+	defaultTimSecret(&ts.Spec)
+	return ts
+}
+
+// parseDirectorySecretName parses the secretName template of a TimSecretDirectory
+func parseDirectorySecretName(text string) (*template.Template, error) {
+	tmpl, err := template.New("secretName").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secretName: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderDirectorySecretName renders the Secret name for a secret below the directory's path
+func renderDirectorySecretName(tmpl *template.Template, dirName, leaf string) (string, error) {
+	var buf bytes.Buffer
+	data := directoryNameData{Directory: dirName, Path: leaf, Name: path.Base(leaf)}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render secretName for %s: %w", leaf, err)
+	}
+
+	name := strings.TrimSpace(buf.String())
+	if msgs := validation.IsDNS1123Subdomain(name); len(msgs) > 0 {
+		return "", fmt.Errorf("secretName for %s rendered %q: %s", leaf, name, strings.Join(msgs, ", "))
+	}
+	return name, nil
+}
+
+// applyTimSecret creates or updates a generated TimSecret. TimSecrets of the
+// same name not generated by the directory are never taken over.
+func (r *TimSecretDirectoryReconciler) applyTimSecret(ctx context.Context, dir *secretsv1alpha1.TimSecretDirectory, desired *secretsv1alpha1.TimSecret) error {
+	ts := &secretsv1alpha1.TimSecret{ObjectMeta: metav1.ObjectMeta{Name: desired.Name, Namespace: dir.Namespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, ts, func() error {
+		if !ts.CreationTimestamp.IsZero() && !metav1.IsControlledBy(ts, dir) {
+			return fmt.Errorf("TimSecret %s/%s already exists and was not generated by TimSecretDirectory %s", ts.Namespace, ts.Name, dir.Name)
+		}
+		if ts.Labels == nil {
+			ts.Labels = map[string]string{}
+		}
+		ts.Labels[DirectoryLabel] = dir.Name
+		ts.Spec = desired.Spec
+		return controllerutil.SetControllerReference(dir, ts, r.Scheme)
+	})
+	return err
+}
+
+// pruneTimSecrets deletes generated TimSecrets whose secret is gone from Vault
+func (r *TimSecretDirectoryReconciler) pruneTimSecrets(ctx context.Context, dir *secretsv1alpha1.TimSecretDirectory, desired []*secretsv1alpha1.TimSecret) (int, error) {
+	existing := &secretsv1alpha1.TimSecretList{}
+	if err := r.List(ctx, existing, client.InNamespace(dir.Namespace), client.MatchingLabels{DirectoryLabel: dir.Name}); err != nil {
+		return 0, err
+	}
+
+	keep := make(map[string]bool, len(desired))
+	for _, ts := range desired {
+		keep[ts.Name] = true
+	}
+
+	pruned := 0
+	for i := range existing.Items {
+		ts := &existing.Items[i]
+		if keep[ts.Name] || !metav1.IsControlledBy(ts, dir) || !ts.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if err := r.Delete(ctx, ts); err != nil && !apierrors.IsNotFound(err) {
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// handleError records a failed sync in the status and retries later
+func (r *TimSecretDirectoryReconciler) handleError(ctx context.Context, dir *secretsv1alpha1.TimSecretDirectory, err error, reason string) (ctrl.Result, error) {
+	dir.Status.LastError = err.Error()
+	meta.SetStatusCondition(&dir.Status.Conditions, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: err.Error(),
+	})
+	if updateErr := r.Status().Update(ctx, dir); updateErr != nil {
+		return ctrl.Result{RequeueAfter: directoryRetryInterval}, fmt.Errorf("failed to update status: %w (original error: %v)", updateErr, err)
+	}
+	return ctrl.Result{RequeueAfter: directoryRetryInterval}, nil
+}
+
+// timSecretReconciler returns a TimSecretReconciler sharing the Vault
+// settings, used to resolve configs the way TimSecrets do
+func (r *TimSecretDirectoryReconciler) timSecretReconciler() *TimSecretReconciler {
+	return &TimSecretReconciler{
+		Client:                       r.Client,
+		Scheme:                       r.Scheme,
+		VaultClients:                 r.VaultClients,
+		RejectInlineTokens:           r.RejectInlineTokens,
+		ServiceAccountTokenAudiences: r.ServiceAccountTokenAudiences,
+		RejectCrossNamespaceConfig:   r.RejectCrossNamespaceConfig,
+		ClusterResourceNamespace:     r.ClusterResourceNamespace,
+		AllowCrossNamespaceTargets:   r.AllowCrossNamespaceTargets,
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TimSecretDirectoryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.VaultClients == nil {
+		r.VaultClients = vault.NewClientCache()
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&secretsv1alpha1.TimSecretDirectory{}).
+		// Status updates of the generated TimSecrets do not need a new listing
+		Owns(&secretsv1alpha1.TimSecret{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
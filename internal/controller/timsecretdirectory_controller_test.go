@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+func TestDirectoryTimSecrets(t *testing.T) {
+	spec := &secretsv1alpha1.TimSecretDirectorySpec{
+		VaultConfigRef: &secretsv1alpha1.VaultConfigReference{Name: "vault"},
+		VaultPath:      "secret/data/myapp",
+	}
+	defaultTimSecretDirectory(spec)
+
+	leaves := []string{"db", "queue/Worker_1"}
+	paths := []string{"secret/data/myapp/db", "secret/data/myapp/queue/Worker_1"}
+
+	timSecrets, err := directoryTimSecrets("myapp", spec, leaves, paths)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, ts := range timSecrets {
+		names = append(names, ts.Name)
+		if ts.Spec.SecretName != ts.Name || len(ts.Spec.Sources) != 0 {
+			t.Errorf("%s: unexpected spec %+v", ts.Name, ts.Spec)
+		}
+	}
+	if want := []string{"myapp-db", "myapp-queue-worker-1"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got names %v, want %v", names, want)
+	}
+	if timSecrets[1].Spec.VaultPath != paths[1] || timSecrets[1].Spec.DeletionPolicy != secretsv1alpha1.DeletionPolicyDelete {
+		t.Errorf("unexpected spec %+v", timSecrets[1].Spec)
+	}
+
+	// An empty listing never yields an empty set of TimSecrets to prune down to
+	if _, err := directoryTimSecrets("myapp", spec, nil, nil); !errors.Is(err, errEmptyDirectory) {
+		t.Errorf("expected errEmptyDirectory for an empty directory, got %v", err)
+	}
+
+	// Names that collide or are not valid Secret names fail the sync
+	spec.SecretName = "{{ .Name | lower }}"
+	if _, err := directoryTimSecrets("myapp", spec, []string{"a/db", "b/db"}, []string{"x/a/db", "x/b/db"}); err == nil {
+		t.Error("expected an error for duplicate names")
+	}
+	spec.SecretName = "{{ .Path }}"
+	if _, err := directoryTimSecrets("myapp", spec, leaves, paths); err == nil {
+		t.Error("expected an error for an invalid name")
+	}
+
+	// Merge reads every secret into one TimSecret, in path order
+	spec.Mode = secretsv1alpha1.DirectoryModeMerge
+	spec.SecretName = "myapp-env"
+	timSecrets, err = directoryTimSecrets("myapp", spec, leaves, paths)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(timSecrets) != 1 || timSecrets[0].Name != "myapp-env" || !reflect.DeepEqual(sourcePaths(&timSecrets[0].Spec), paths) {
+		t.Errorf("unexpected TimSecrets %+v", timSecrets)
+	}
+	if _, err := directoryTimSecrets("myapp", spec, nil, nil); err == nil {
+		t.Error("expected an error for an empty directory")
+	}
+}
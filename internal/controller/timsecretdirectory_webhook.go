@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+// TimSecretDirectoryWebhook defaults TimSecretDirectories and rejects those
+// whose TimSecrets the TimSecret webhook would reject
+type TimSecretDirectoryWebhook struct {
+	client.Client
+
+	// AllowCrossNamespaceTargets lets TimSecrets target any namespace
+	AllowCrossNamespaceTargets bool
+
+	// RejectInlineTokens refuses the deprecated inline vaultToken field
+	RejectInlineTokens bool
+
+	// RejectCrossNamespaceConfig refuses vaultConfigNamespace references to other namespaces
+	RejectCrossNamespaceConfig bool
+}
+
+// +kubebuilder:webhook:path=/mutate-secrets-tim-operator-v1alpha1-timsecretdirectory,mutating=true,failurePolicy=fail,sideEffects=None,groups=secrets.tim.operator,resources=timsecretdirectories,verbs=create;update,versions=v1alpha1,name=mtimsecretdirectory.secrets.tim.operator,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-secrets-tim-operator-v1alpha1-timsecretdirectory,mutating=false,failurePolicy=fail,sideEffects=None,groups=secrets.tim.operator,resources=timsecretdirectories,verbs=create;update,versions=v1alpha1,name=vtimsecretdirectory.secrets.tim.operator,admissionReviewVersions=v1
+
+var _ admission.CustomDefaulter = &TimSecretDirectoryWebhook{}
+var _ admission.CustomValidator = &TimSecretDirectoryWebhook{}
+
+// Default implements admission.CustomDefaulter
+func (w *TimSecretDirectoryWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	dir, ok := obj.(*secretsv1alpha1.TimSecretDirectory)
+	if !ok {
+		return fmt.Errorf("expected a TimSecretDirectory, got %T", obj)
+	}
+	defaultTimSecretDirectory(&dir.Spec)
+	return nil
+}
+
+// ValidateCreate implements admission.CustomValidator
+func (w *TimSecretDirectoryWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	dir, ok := obj.(*secretsv1alpha1.TimSecretDirectory)
+	if !ok {
+		return nil, fmt.Errorf("expected a TimSecretDirectory, got %T", obj)
+	}
+	return w.validate(ctx, dir, nil)
+}
+
+// ValidateUpdate implements admission.CustomValidator. Like for TimSecrets,
+// updates leaving the spec alone are always allowed.
+func (w *TimSecretDirectoryWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldDir, ok := oldObj.(*secretsv1alpha1.TimSecretDirectory)
+	if !ok {
+		return nil, fmt.Errorf("expected a TimSecretDirectory, got %T", oldObj)
+	}
+	dir, ok := newObj.(*secretsv1alpha1.TimSecretDirectory)
+	if !ok {
+		return nil, fmt.Errorf("expected a TimSecretDirectory, got %T", newObj)
+	}
+
+	if dir.DeletionTimestamp != nil || equality.Semantic.DeepEqual(oldDir.Spec, dir.Spec) {
+		return nil, nil
+	}
+	return w.validate(ctx, dir, oldDir)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (w *TimSecretDirectoryWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the spec of a TimSecretDirectory and its configuration
+// reference. On update, old is the previous directory and the configuration
+// is only looked up again when the reference changed.
+func (w *TimSecretDirectoryWebhook) validate(ctx context.Context, dir, old *secretsv1alpha1.TimSecretDirectory) (admission.Warnings, error) {
+	specPath := field.NewPath("spec")
+	errs, warnings := validateTimSecretDirectorySpec(&dir.Spec, specPath)
+
+	// Generated TimSecrets reference the same configuration, so it is
+	// checked the way the TimSecret webhook checks theirs
+	if len(errs) == 0 && (old == nil || !equality.Semantic.DeepEqual(old.Spec.VaultConfigRef, dir.Spec.VaultConfigRef)) {
+		ts := &secretsv1alpha1.TimSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: dir.Name, Namespace: dir.Namespace},
+			Spec:       secretsv1alpha1.TimSecretSpec{VaultConfigRef: dir.Spec.VaultConfigRef},
+		}
+		configErrs, err := w.timSecretWebhook().validateConfigReference(ctx, ts, specPath)
+		if err != nil {
+			return warnings, err
+		}
+		errs = append(errs, configErrs...)
+	}
+
+	if len(errs) > 0 {
+		return warnings, apierrors.NewInvalid(secretsv1alpha1.GroupVersion.WithKind("TimSecretDirectory").GroupKind(), dir.Name, errs)
+	}
+	return warnings, nil
+}
+
+// timSecretWebhook returns a TimSecretWebhook with the same policies, used
+// to check the directory the way its TimSecrets will be checked
+func (w *TimSecretDirectoryWebhook) timSecretWebhook() *TimSecretWebhook {
+	return &TimSecretWebhook{
+		Client:                     w.Client,
+		AllowCrossNamespaceTargets: w.AllowCrossNamespaceTargets,
+		RejectInlineTokens:         w.RejectInlineTokens,
+		RejectCrossNamespaceConfig: w.RejectCrossNamespaceConfig,
+	}
+}
+
+// SetupWebhookWithManager registers the webhooks with the Manager.
+func (w *TimSecretDirectoryWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&secretsv1alpha1.TimSecretDirectory{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
@@ -0,0 +1,635 @@
+package controller
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	apimachineryvalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+// validationNamespace is the namespace used to check that namespace templates render
+const validationNamespace = "default"
+
+// defaultTimSecret applies the defaults of a TimSecret spec
+func defaultTimSecret(spec *secretsv1alpha1.TimSecretSpec) {
+	if spec.SyncInterval == "" {
+		spec.SyncInterval = "5m"
+	}
+	if spec.VaultConfigRef != nil && spec.VaultConfigRef.Kind == "" {
+		spec.VaultConfigRef.Kind = "TimSecretConfig"
+	}
+	if spec.DeletionPolicy == "" {
+		spec.DeletionPolicy = secretsv1alpha1.DeletionPolicyDelete
+	}
+	if spec.CreationPolicy == "" {
+		spec.CreationPolicy = secretsv1alpha1.CreationPolicyOwner
+	}
+	if spec.ConflictPolicy == "" {
+		spec.ConflictPolicy = secretsv1alpha1.ConflictPolicyError
+	}
+}
+
+// setDeprecatedCondition records the deprecation warnings of a TimSecret in
+// its Deprecated condition, removing it once there are none. It reports
+// whether the warnings changed, so they are announced once instead of on
+// every sync.
+func setDeprecatedCondition(status *secretsv1alpha1.TimSecretStatus, warnings []string) bool {
+	current := meta.FindStatusCondition(status.Conditions, "Deprecated")
+	if len(warnings) == 0 {
+		meta.RemoveStatusCondition(&status.Conditions, "Deprecated")
+		return current != nil
+	}
+
+	message := strings.Join(warnings, "; ")
+	if current != nil && current.Message == message {
+		return false
+	}
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:    "Deprecated",
+		Status:  metav1.ConditionTrue,
+		Reason:  "DeprecatedSpec",
+		Message: message,
+	})
+	return true
+}
+
+// normalizeLegacyTimSecretSpec rewrites the field combinations that TimSecrets
+// created before specs were validated may still use into the valid spec they
+// were synced as: complete direct values win over a config reference, which
+// wins over incomplete direct values, and an unparsable syncInterval means the
+// 5m default. It returns a warning for each rewrite.
+func normalizeLegacyTimSecretSpec(spec *secretsv1alpha1.TimSecretSpec) []string {
+	var warnings []string
+
+	hasConfig := spec.VaultConfigRef != nil || spec.VaultConfig != ""
+	hasDirect := spec.VaultURL != "" || spec.VaultToken != "" || spec.TokenSecretRef != nil
+	if hasConfig && hasDirect {
+		if spec.VaultURL != "" && (spec.VaultToken != "" || spec.TokenSecretRef != nil) {
+			spec.VaultConfigRef, spec.VaultConfig, spec.VaultConfigNamespace = nil, "", ""
+			warnings = append(warnings, "spec.vaultURL is combined with a config reference; the direct values are used, remove vaultConfigRef or vaultConfig")
+		} else {
+			spec.VaultURL, spec.VaultToken, spec.TokenSecretRef = "", "", nil
+			warnings = append(warnings, "incomplete direct values are combined with a config reference; the config is used, remove vaultURL, vaultToken and tokenSecretRef")
+		}
+	}
+
+	if spec.SyncInterval != "" {
+		if _, err := time.ParseDuration(spec.SyncInterval); err != nil {
+			warnings = append(warnings, fmt.Sprintf("spec.syncInterval %q is not a duration; the default of 5m is used", spec.SyncInterval))
+			spec.SyncInterval = "5m"
+		}
+	}
+
+	return warnings
+}
+
+// validateTimSecretSpec checks a TimSecret spec without looking up other objects
+func validateTimSecretSpec(spec *secretsv1alpha1.TimSecretSpec, path *field.Path) (field.ErrorList, []string) {
+	var errs field.ErrorList
+	var warnings []string
+
+	hasRef := spec.VaultConfigRef != nil
+	hasConfig := spec.VaultConfig != ""
+	hasDirect := spec.VaultURL != "" || spec.VaultToken != "" || spec.TokenSecretRef != nil
+
+	switch {
+	case hasRef && hasConfig:
+		errs = append(errs, field.Forbidden(path.Child("vaultConfig"), "may not be combined with vaultConfigRef"))
+	case (hasRef || hasConfig) && hasDirect:
+		errs = append(errs, field.Forbidden(path.Child("vaultURL"), "direct values may not be combined with vaultConfigRef or vaultConfig"))
+	case !hasRef && !hasConfig && !hasDirect:
+		errs = append(errs, field.Required(path, "one of vaultConfigRef, vaultConfig or vaultURL with tokenSecretRef must be specified"))
+	}
+
+	if hasRef {
+		refPath := path.Child("vaultConfigRef")
+		switch spec.VaultConfigRef.Kind {
+		case "", "TimSecretConfig", "ClusterTimSecretConfig":
+		default:
+			errs = append(errs, field.NotSupported(refPath.Child("kind"), spec.VaultConfigRef.Kind, []string{"TimSecretConfig", "ClusterTimSecretConfig"}))
+		}
+		if spec.VaultConfigRef.Name == "" {
+			errs = append(errs, field.Required(refPath.Child("name"), ""))
+		}
+	}
+
+	if spec.VaultConfigNamespace != "" {
+		if !hasConfig {
+			errs = append(errs, field.Forbidden(path.Child("vaultConfigNamespace"), "requires vaultConfig"))
+		}
+		warnings = append(warnings, "spec.vaultConfigNamespace is deprecated, use a ClusterTimSecretConfig instead")
+	}
+
+	if hasDirect {
+		if spec.VaultURL == "" {
+			errs = append(errs, field.Required(path.Child("vaultURL"), "required with tokenSecretRef or vaultToken"))
+		} else if err := validateVaultURL(spec.VaultURL); err != nil {
+			errs = append(errs, field.Invalid(path.Child("vaultURL"), spec.VaultURL, err.Error()))
+		}
+		errs = append(errs, validateTokenSource(spec.VaultToken, spec.TokenSecretRef, path)...)
+	}
+	if spec.VaultToken != "" {
+		warnings = append(warnings, "spec.vaultToken is deprecated, use tokenSecretRef instead")
+	}
+
+	if err := validateVaultPath(spec.VaultPath); err != nil {
+		errs = append(errs, field.Invalid(path.Child("vaultPath"), spec.VaultPath, err.Error()))
+	}
+	if spec.Version < 0 {
+		errs = append(errs, field.Invalid(path.Child("version"), spec.Version, "must be at least 1"))
+	}
+	seenPaths := map[string]bool{spec.VaultPath: true}
+	for i, source := range spec.Sources {
+		sourcePath := path.Child("sources").Index(i).Child("vaultPath")
+		if err := validateVaultPath(source.VaultPath); err != nil {
+			errs = append(errs, field.Invalid(sourcePath, source.VaultPath, err.Error()))
+		} else if seenPaths[source.VaultPath] {
+			errs = append(errs, field.Duplicate(sourcePath, source.VaultPath))
+		}
+		seenPaths[source.VaultPath] = true
+	}
+	switch spec.ConflictPolicy {
+	case "", secretsv1alpha1.ConflictPolicyError, secretsv1alpha1.ConflictPolicyFirstWins, secretsv1alpha1.ConflictPolicyLastWins:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("conflictPolicy"), spec.ConflictPolicy, []string{"Error", "FirstWins", "LastWins"}))
+	}
+
+	if spec.SecretName == "" {
+		errs = append(errs, field.Required(path.Child("secretName"), ""))
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(spec.SecretName) {
+			errs = append(errs, field.Invalid(path.Child("secretName"), spec.SecretName, msg))
+		}
+	}
+
+	if spec.DeploymentName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(spec.DeploymentName) {
+			errs = append(errs, field.Invalid(path.Child("deploymentName"), spec.DeploymentName, msg))
+		}
+	}
+
+	if spec.Namespace != "" {
+		for _, msg := range validation.IsDNS1123Label(spec.Namespace) {
+			errs = append(errs, field.Invalid(path.Child("namespace"), spec.Namespace, msg))
+		}
+	}
+
+	if spec.SyncInterval != "" {
+		interval, err := time.ParseDuration(spec.SyncInterval)
+		switch {
+		case err != nil:
+			errs = append(errs, field.Invalid(path.Child("syncInterval"), spec.SyncInterval, "must be a duration such as 30s, 5m or 1h"))
+		case interval < 30*time.Second:
+			warnings = append(warnings, fmt.Sprintf("spec.syncInterval %s is below the minimum and will be raised to 30s", spec.SyncInterval))
+		case interval > 1*time.Hour:
+			warnings = append(warnings, fmt.Sprintf("spec.syncInterval %s is above the maximum and will be lowered to 1h", spec.SyncInterval))
+		}
+	}
+
+	switch spec.DeletionPolicy {
+	case "", secretsv1alpha1.DeletionPolicyDelete, secretsv1alpha1.DeletionPolicyRetain, secretsv1alpha1.DeletionPolicyOrphan:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("deletionPolicy"), spec.DeletionPolicy, []string{"Delete", "Retain", "Orphan"}))
+	}
+
+	errs = append(errs, validateDataSelection(spec.Data, spec.DataFrom, path)...)
+
+	if target := spec.Target; target != nil {
+		targetPath := path.Child("target")
+		switch target.Type {
+		case "", corev1.SecretTypeOpaque, corev1.SecretTypeTLS, corev1.SecretTypeDockerConfigJson, corev1.SecretTypeBasicAuth, corev1.SecretTypeSSHAuth:
+		default:
+			errs = append(errs, field.NotSupported(targetPath.Child("type"), target.Type, []string{
+				string(corev1.SecretTypeOpaque), string(corev1.SecretTypeTLS), string(corev1.SecretTypeDockerConfigJson),
+				string(corev1.SecretTypeBasicAuth), string(corev1.SecretTypeSSHAuth),
+			}))
+		}
+		if tmpl := target.Template; tmpl != nil {
+			metadataPath := targetPath.Child("template", "metadata")
+			errs = append(errs, metav1validation.ValidateLabels(tmpl.Metadata.Labels, metadataPath.Child("labels"))...)
+			errs = append(errs, apimachineryvalidation.ValidateAnnotations(tmpl.Metadata.Annotations, metadataPath.Child("annotations"))...)
+			for key := range tmpl.Metadata.Labels {
+				if strings.HasPrefix(key, operatorMetadataPrefix) {
+					errs = append(errs, field.Forbidden(metadataPath.Child("labels").Key(key), "the "+operatorMetadataPrefix+" prefix is reserved for the operator"))
+				}
+			}
+			for key := range tmpl.Metadata.Annotations {
+				if strings.HasPrefix(key, operatorMetadataPrefix) {
+					errs = append(errs, field.Forbidden(metadataPath.Child("annotations").Key(key), "the "+operatorMetadataPrefix+" prefix is reserved for the operator"))
+				}
+			}
+			dataPath := targetPath.Child("template", "data")
+			for key, text := range tmpl.Data {
+				for _, msg := range validation.IsConfigMapKey(key) {
+					errs = append(errs, field.Invalid(dataPath.Key(key), key, msg))
+				}
+				if _, err := parseDataTemplates(map[string]string{key: text}); err != nil {
+					errs = append(errs, field.Invalid(dataPath.Key(key), text, err.Error()))
+				}
+			}
+			switch tmpl.MergePolicy {
+			case "", secretsv1alpha1.TemplateMergePolicyReplace, secretsv1alpha1.TemplateMergePolicyMerge:
+			default:
+				errs = append(errs, field.NotSupported(targetPath.Child("template", "mergePolicy"), tmpl.MergePolicy, []string{"Replace", "Merge"}))
+			}
+		}
+		if format := target.Format; format != nil {
+			formatPath := targetPath.Child("format")
+			if _, ok := defaultFormatKeys[format.Type]; !ok {
+				errs = append(errs, field.NotSupported(formatPath.Child("type"), format.Type, []string{"dotenv", "json", "yaml", "properties", "toml"}))
+			}
+			if format.Key != "" {
+				for _, msg := range validation.IsConfigMapKey(format.Key) {
+					errs = append(errs, field.Invalid(formatPath.Child("key"), format.Key, msg))
+				}
+			}
+			if target.Type != "" && target.Type != corev1.SecretTypeOpaque && !format.KeepKeys {
+				errs = append(errs, field.Forbidden(formatPath, "requires keepKeys with target.type "+string(target.Type)+", which needs its individual keys"))
+			}
+		}
+		if target.Immutable && spec.CreationPolicy == secretsv1alpha1.CreationPolicyMerge {
+			errs = append(errs, field.Forbidden(targetPath.Child("immutable"), "may not be combined with creationPolicy Merge, the Secret is not owned by the TimSecret"))
+		}
+		for secretKey, vaultKey := range target.KeyMapping {
+			for _, msg := range validation.IsConfigMapKey(secretKey) {
+				errs = append(errs, field.Invalid(targetPath.Child("keyMapping").Key(secretKey), secretKey, msg))
+			}
+			if vaultKey == "" {
+				errs = append(errs, field.Required(targetPath.Child("keyMapping").Key(secretKey), "must name a Vault key"))
+			}
+		}
+	}
+
+	switch spec.CreationPolicy {
+	case "", secretsv1alpha1.CreationPolicyOwner, secretsv1alpha1.CreationPolicyAdopt, secretsv1alpha1.CreationPolicyMerge:
+	case secretsv1alpha1.CreationPolicyNone:
+		if spec.DeploymentName != "" {
+			warnings = append(warnings, "spec.deploymentName is ignored with creationPolicy None, the Secret is never written")
+		}
+	default:
+		errs = append(errs, field.NotSupported(path.Child("creationPolicy"), spec.CreationPolicy, []string{"Owner", "Adopt", "Merge", "None"}))
+	}
+
+	return errs, warnings
+}
+
+// validateDataSelection checks spec.data and spec.dataFrom
+func validateDataSelection(entries []secretsv1alpha1.SecretDataEntry, rules []secretsv1alpha1.SecretDataFromRule, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	seen := map[string]bool{}
+	for i, entry := range entries {
+		entryPath := path.Child("data").Index(i)
+		for _, msg := range validation.IsConfigMapKey(entry.SecretKey) {
+			errs = append(errs, field.Invalid(entryPath.Child("secretKey"), entry.SecretKey, msg))
+		}
+		if seen[entry.SecretKey] {
+			errs = append(errs, field.Duplicate(entryPath.Child("secretKey"), entry.SecretKey))
+		}
+		seen[entry.SecretKey] = true
+		if entry.RemoteKey == "" {
+			errs = append(errs, field.Required(entryPath.Child("remoteKey"), "must name a Vault key"))
+		}
+	}
+
+	for i, rule := range rules {
+		rulePath := path.Child("dataFrom").Index(i)
+		for j, pattern := range rule.Include {
+			if _, err := regexp.Compile(pattern); err != nil {
+				errs = append(errs, field.Invalid(rulePath.Child("include").Index(j), pattern, err.Error()))
+			}
+		}
+		for j, pattern := range rule.Exclude {
+			if _, err := regexp.Compile(pattern); err != nil {
+				errs = append(errs, field.Invalid(rulePath.Child("exclude").Index(j), pattern, err.Error()))
+			}
+		}
+		for j, rewrite := range rule.Rewrite {
+			if _, err := regexp.Compile(rewrite.Regexp); err != nil {
+				errs = append(errs, field.Invalid(rulePath.Child("rewrite").Index(j).Child("regexp"), rewrite.Regexp, err.Error()))
+			}
+		}
+		switch rule.Conversion {
+		case "", secretsv1alpha1.KeyConversionUpper, secretsv1alpha1.KeyConversionLower, secretsv1alpha1.KeyConversionUpperSnake, secretsv1alpha1.KeyConversionLowerSnake:
+		default:
+			errs = append(errs, field.NotSupported(rulePath.Child("conversion"), rule.Conversion, []string{"Upper", "Lower", "UpperSnake", "LowerSnake"}))
+		}
+	}
+	return errs
+}
+
+// defaultTimSecretDirectory applies the defaults of a TimSecretDirectory spec
+func defaultTimSecretDirectory(spec *secretsv1alpha1.TimSecretDirectorySpec) {
+	if spec.Mode == "" {
+		spec.Mode = secretsv1alpha1.DirectoryModeSecretPerLeaf
+	}
+	if spec.Mode == secretsv1alpha1.DirectoryModeSecretPerLeaf && spec.SecretName == "" {
+		spec.SecretName = defaultDirectorySecretName
+	}
+	if spec.MaxSecrets == 0 {
+		spec.MaxSecrets = 100
+	}
+	if spec.SyncInterval == "" {
+		spec.SyncInterval = "5m"
+	}
+	if spec.VaultConfigRef != nil && spec.VaultConfigRef.Kind == "" {
+		spec.VaultConfigRef.Kind = "TimSecretConfig"
+	}
+}
+
+// validateTimSecretDirectorySpec checks a TimSecretDirectory spec. The
+// settings passed on to the generated TimSecrets are checked as a TimSecret.
+func validateTimSecretDirectorySpec(spec *secretsv1alpha1.TimSecretDirectorySpec, path *field.Path) (field.ErrorList, []string) {
+	var errs field.ErrorList
+
+	if spec.VaultConfigRef == nil {
+		errs = append(errs, field.Required(path.Child("vaultConfigRef"), ""))
+	}
+
+	switch spec.Mode {
+	case "", secretsv1alpha1.DirectoryModeSecretPerLeaf:
+		if spec.SecretName != "" {
+			if _, err := parseDirectorySecretName(spec.SecretName); err != nil {
+				errs = append(errs, field.Invalid(path.Child("secretName"), spec.SecretName, err.Error()))
+			}
+		}
+	case secretsv1alpha1.DirectoryModeMerge:
+		if spec.SecretName == "" {
+			errs = append(errs, field.Required(path.Child("secretName"), "required with mode Merge"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(path.Child("mode"), spec.Mode, []string{"SecretPerLeaf", "Merge"}))
+	}
+
+	if spec.MaxSecrets < 0 {
+		errs = append(errs, field.Invalid(path.Child("maxSecrets"), spec.MaxSecrets, "must be at least 1"))
+	}
+
+	// Check the generated TimSecrets with a placeholder Secret name; their
+	// fields share the names of the directory's
+	secretName := spec.SecretName
+	if spec.Mode != secretsv1alpha1.DirectoryModeMerge {
+		secretName = "example"
+	}
+	timSecretErrs, warnings := validateTimSecretSpec(&secretsv1alpha1.TimSecretSpec{
+		VaultConfigRef: spec.VaultConfigRef,
+		VaultNamespace: spec.VaultNamespace,
+		VaultPath:      spec.VaultPath,
+		SecretName:     secretName,
+		Target:         spec.Target,
+		SyncInterval:   spec.SyncInterval,
+		DeletionPolicy: spec.DeletionPolicy,
+		CreationPolicy: spec.CreationPolicy,
+		ConflictPolicy: spec.ConflictPolicy,
+	}, path)
+	for _, err := range timSecretErrs {
+		// vaultConfigRef is reported above already
+		if err.Type == field.ErrorTypeRequired && err.Field == path.String() {
+			continue
+		}
+		errs = append(errs, err)
+	}
+	return errs, warnings
+}
+
+// defaultTimSecretConfigSpec applies the defaults of a TimSecretConfig spec
+func defaultTimSecretConfigSpec(spec *secretsv1alpha1.TimSecretConfigSpec) {
+	if auth := spec.Auth; auth != nil {
+		if auth.Kubernetes != nil && auth.Kubernetes.MountPath == "" {
+			auth.Kubernetes.MountPath = "kubernetes"
+		}
+		if auth.JWT != nil && auth.JWT.MountPath == "" {
+			auth.JWT.MountPath = "jwt"
+		}
+		if auth.Cert != nil && auth.Cert.MountPath == "" {
+			auth.Cert.MountPath = "cert"
+		}
+		if appRole := auth.AppRole; appRole != nil {
+			if appRole.MountPath == "" {
+				appRole.MountPath = "approle"
+			}
+			if appRole.SecretRef.RoleIDKey == "" {
+				appRole.SecretRef.RoleIDKey = "role_id"
+			}
+			if appRole.SecretRef.SecretIDKey == "" {
+				appRole.SecretRef.SecretIDKey = "secret_id"
+			}
+		}
+	}
+
+	if spec.TLS != nil && spec.TLS.CA != nil {
+		if spec.TLS.CA.Kind == "" {
+			spec.TLS.CA.Kind = "ConfigMap"
+		}
+		if spec.TLS.CA.Key == "" {
+			spec.TLS.CA.Key = "ca.crt"
+		}
+	}
+}
+
+// validateTimSecretConfigSpec checks a TimSecretConfig spec without looking up
+// other objects. clusterScoped is set for the spec of a ClusterTimSecretConfig.
+func validateTimSecretConfigSpec(spec *secretsv1alpha1.TimSecretConfigSpec, clusterScoped bool, path *field.Path) (field.ErrorList, []string) {
+	var errs field.ErrorList
+	var warnings []string
+
+	if spec.VaultURL == "" {
+		errs = append(errs, field.Required(path.Child("vaultURL"), ""))
+	} else if err := validateVaultURL(spec.VaultURL); err != nil {
+		errs = append(errs, field.Invalid(path.Child("vaultURL"), spec.VaultURL, err.Error()))
+	}
+
+	if spec.Auth != nil {
+		if spec.VaultToken != "" || spec.TokenSecretRef != nil {
+			errs = append(errs, field.Forbidden(path.Child("auth"), "may not be combined with tokenSecretRef or vaultToken"))
+		}
+		errs = append(errs, validateVaultAuth(spec.Auth, spec.TLS, clusterScoped, path.Child("auth"))...)
+	} else {
+		errs = append(errs, validateTokenSource(spec.VaultToken, spec.TokenSecretRef, path)...)
+	}
+	if spec.VaultToken != "" {
+		warnings = append(warnings, "spec.vaultToken is deprecated, use tokenSecretRef instead")
+	}
+
+	if tls := spec.TLS; tls != nil {
+		tlsPath := path.Child("tls")
+		if tls.CA != nil {
+			switch tls.CA.Kind {
+			case "", "ConfigMap", "Secret":
+			default:
+				errs = append(errs, field.NotSupported(tlsPath.Child("ca", "kind"), tls.CA.Kind, []string{"ConfigMap", "Secret"}))
+			}
+			if tls.CA.Name == "" {
+				errs = append(errs, field.Required(tlsPath.Child("ca", "name"), ""))
+			}
+		}
+		if tls.ClientCertSecretRef != nil && tls.ClientCertSecretRef.Name == "" {
+			errs = append(errs, field.Required(tlsPath.Child("clientCertSecretRef", "name"), ""))
+		}
+		if tls.InsecureSkipVerify {
+			warnings = append(warnings, "spec.tls.insecureSkipVerify disables Vault server certificate verification, only use it for development")
+		}
+	}
+
+	for i, rule := range spec.AllowedPaths {
+		rulePath := path.Child("allowedPaths").Index(i)
+		if len(rule.Paths) == 0 {
+			errs = append(errs, field.Required(rulePath.Child("paths"), ""))
+		}
+		for j, pattern := range rule.Paths {
+			if _, err := renderNamespaceTemplate("allowedPaths", pattern, validationNamespace); err != nil {
+				errs = append(errs, field.Invalid(rulePath.Child("paths").Index(j), pattern, err.Error()))
+			}
+		}
+	}
+
+	return errs, warnings
+}
+
+// validateClusterTimSecretConfigSpec checks a ClusterTimSecretConfig spec without looking up other objects
+func validateClusterTimSecretConfigSpec(spec *secretsv1alpha1.ClusterTimSecretConfigSpec, path *field.Path) (field.ErrorList, []string) {
+	errs, warnings := validateTimSecretConfigSpec(&spec.TimSecretConfigSpec, true, path)
+
+	if spec.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(spec.NamespaceSelector); err != nil {
+			errs = append(errs, field.Invalid(path.Child("namespaceSelector"), spec.NamespaceSelector, err.Error()))
+		}
+	}
+	for i, namespace := range spec.AllowedNamespaces {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			errs = append(errs, field.Invalid(path.Child("allowedNamespaces").Index(i), namespace, msg))
+		}
+	}
+	if spec.NamespaceSelector == nil && len(spec.AllowedNamespaces) == 0 {
+		warnings = append(warnings, "neither spec.namespaceSelector nor spec.allowedNamespaces is set, no namespace may use this config")
+	}
+
+	return errs, warnings
+}
+
+// validateVaultAuth checks that exactly one, complete auth method is configured.
+// Only cluster-scoped configs may log in with the operator's own ServiceAccount token.
+func validateVaultAuth(auth *secretsv1alpha1.VaultAuth, tls *secretsv1alpha1.VaultTLS, clusterScoped bool, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	if n := authMethodCount(auth); n != 1 {
+		errs = append(errs, field.Invalid(path, fmt.Sprintf("%d auth methods", n), "must specify exactly one of kubernetes, appRole, jwt or cert"))
+	}
+
+	if auth.RoleTemplate != "" {
+		if auth.AppRole != nil {
+			errs = append(errs, field.Forbidden(path.Child("roleTemplate"), "is not supported with appRole"))
+		}
+		if _, err := renderRoleTemplate(auth.RoleTemplate, validationNamespace); err != nil {
+			errs = append(errs, field.Invalid(path.Child("roleTemplate"), auth.RoleTemplate, err.Error()))
+		}
+	}
+
+	if k8s := auth.Kubernetes; k8s != nil {
+		if k8s.Role == "" && auth.RoleTemplate == "" {
+			errs = append(errs, field.Required(path.Child("kubernetes", "role"), "required unless auth.roleTemplate is set"))
+		}
+		switch {
+		case k8s.ServiceAccountName == "" && !clusterScoped:
+			errs = append(errs, field.Required(path.Child("kubernetes", "serviceAccountName"), "only ClusterTimSecretConfigs may use the operator's ServiceAccount token"))
+		case k8s.Audience != "" && k8s.ServiceAccountName == "":
+			errs = append(errs, field.Required(path.Child("kubernetes", "serviceAccountName"), "required with audience"))
+		}
+	}
+
+	if jwt := auth.JWT; jwt != nil {
+		if jwt.Role == "" && auth.RoleTemplate == "" {
+			errs = append(errs, field.Required(path.Child("jwt", "role"), "required unless auth.roleTemplate is set"))
+		}
+		switch {
+		case jwt.ServiceAccountName == "" && !clusterScoped:
+			errs = append(errs, field.Required(path.Child("jwt", "serviceAccountName"), "only ClusterTimSecretConfigs may use the operator's ServiceAccount token"))
+		case jwt.Audience != "" && jwt.ServiceAccountName == "":
+			errs = append(errs, field.Required(path.Child("jwt", "serviceAccountName"), "required with audience"))
+		}
+	}
+
+	if auth.AppRole != nil && auth.AppRole.SecretRef.Name == "" {
+		errs = append(errs, field.Required(path.Child("appRole", "secretRef", "name"), ""))
+	}
+
+	if auth.Cert != nil {
+		if auth.Cert.SecretRef.Name == "" {
+			errs = append(errs, field.Required(path.Child("cert", "secretRef", "name"), ""))
+		}
+		if tls != nil && tls.ClientCertSecretRef != nil {
+			errs = append(errs, field.Forbidden(path.Child("cert"), "may not be combined with tls.clientCertSecretRef, auth.cert provides the client certificate"))
+		}
+	}
+
+	return errs
+}
+
+// validateTokenSource checks that exactly one of an inline token and a token Secret reference is set
+func validateTokenSource(inline string, ref *secretsv1alpha1.SecretKeySelector, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	switch {
+	case inline != "" && ref != nil:
+		errs = append(errs, field.Forbidden(path.Child("vaultToken"), "may not be combined with tokenSecretRef"))
+	case inline == "" && ref == nil:
+		errs = append(errs, field.Required(path.Child("tokenSecretRef"), "a Vault token or auth method must be specified"))
+	}
+
+	if ref != nil {
+		if ref.Name == "" {
+			errs = append(errs, field.Required(path.Child("tokenSecretRef", "name"), ""))
+		}
+		if ref.Key == "" {
+			errs = append(errs, field.Required(path.Child("tokenSecretRef", "key"), ""))
+		}
+	}
+
+	return errs
+}
+
+// validateVaultURL checks that a Vault address is an absolute http(s) URL
+func validateVaultURL(address string) error {
+	u, err := url.Parse(address)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("must use the http or https scheme")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("must include a host")
+	}
+	return nil
+}
+
+// validateVaultPath checks the syntax of a Vault path
+func validateVaultPath(path string) error {
+	if strings.Trim(path, "/") == "" {
+		return fmt.Errorf("must not be empty")
+	}
+	if strings.ContainsAny(path, " \t\r\n") {
+		return fmt.Errorf("must not contain whitespace")
+	}
+
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		switch segment {
+		case "":
+			return fmt.Errorf("must not contain empty segments")
+		case ".", "..":
+			return fmt.Errorf("must not contain relative segments")
+		}
+	}
+	return nil
+}
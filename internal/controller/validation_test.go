@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+)
+
+func TestValidateVaultPath(t *testing.T) {
+	valid := []string{"secret/data/myapp", "/secret/data/myapp/", "kv/team-a/db"}
+	for _, path := range valid {
+		if err := validateVaultPath(path); err != nil {
+			t.Errorf("validateVaultPath(%q) returned unexpected error: %v", path, err)
+		}
+	}
+
+	invalid := []string{"", "/", "secret//myapp", "secret/data/../other", "secret/my app"}
+	for _, path := range invalid {
+		if err := validateVaultPath(path); err == nil {
+			t.Errorf("validateVaultPath(%q) expected an error", path)
+		}
+	}
+}
+
+func TestValidateTimSecretSpec(t *testing.T) {
+	valid := secretsv1alpha1.TimSecretSpec{
+		VaultConfigRef: &secretsv1alpha1.VaultConfigReference{Kind: "ClusterTimSecretConfig", Name: "vault"},
+		VaultPath:      "secret/data/myapp",
+		SecretName:     "myapp-secrets",
+		SyncInterval:   "10s",
+	}
+
+	errs, warnings := validateTimSecretSpec(&valid, field.NewPath("spec"))
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected a warning for the clamped sync interval, got %v", warnings)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(spec *secretsv1alpha1.TimSecretSpec)
+	}{
+		{name: "config reference and direct values", modify: func(spec *secretsv1alpha1.TimSecretSpec) { spec.VaultURL = "https://vault:8200" }},
+		{name: "both config references", modify: func(spec *secretsv1alpha1.TimSecretSpec) { spec.VaultConfig = "vault" }},
+		{name: "no configuration", modify: func(spec *secretsv1alpha1.TimSecretSpec) { spec.VaultConfigRef = nil }},
+		{name: "invalid secret name", modify: func(spec *secretsv1alpha1.TimSecretSpec) { spec.SecretName = "My_Secret" }},
+		{name: "invalid sync interval", modify: func(spec *secretsv1alpha1.TimSecretSpec) { spec.SyncInterval = "5 minutes" }},
+		{name: "negative version", modify: func(spec *secretsv1alpha1.TimSecretSpec) { spec.Version = -1 }},
+		{name: "invalid data template", modify: func(spec *secretsv1alpha1.TimSecretSpec) {
+			spec.Target = &secretsv1alpha1.SecretTarget{Template: &secretsv1alpha1.SecretTemplate{Data: map[string]string{"url": "{{ .host"}}}
+		}},
+		{name: "duplicate source", modify: func(spec *secretsv1alpha1.TimSecretSpec) {
+			spec.Sources = []secretsv1alpha1.VaultSource{{VaultPath: "secret/data/myapp"}}
+		}},
+		{name: "invalid dataFrom regexp", modify: func(spec *secretsv1alpha1.TimSecretSpec) {
+			spec.DataFrom = []secretsv1alpha1.SecretDataFromRule{{Include: []string{"db-("}}}
+		}},
+		{name: "duplicate data secret key", modify: func(spec *secretsv1alpha1.TimSecretSpec) {
+			spec.Data = []secretsv1alpha1.SecretDataEntry{{SecretKey: "url", RemoteKey: "a"}, {SecretKey: "url", RemoteKey: "b"}}
+		}},
+		{name: "format replacing typed keys", modify: func(spec *secretsv1alpha1.TimSecretSpec) {
+			spec.Target = &secretsv1alpha1.SecretTarget{Type: "kubernetes.io/tls", Format: &secretsv1alpha1.SecretFormat{Type: "json"}}
+		}},
+	}
+
+	for _, tt := range tests {
+		spec := *valid.DeepCopy()
+		tt.modify(&spec)
+		if errs, _ := validateTimSecretSpec(&spec, field.NewPath("spec")); len(errs) == 0 {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestNormalizeLegacyTimSecretSpec(t *testing.T) {
+	// Complete direct values used to win over vaultConfig
+	spec := secretsv1alpha1.TimSecretSpec{
+		VaultConfig:    "vault",
+		VaultURL:       "https://vault:8200",
+		TokenSecretRef: &secretsv1alpha1.SecretKeySelector{Name: "vault-token", Key: "token"},
+		VaultPath:      "secret/data/myapp",
+		SecretName:     "myapp-secrets",
+		SyncInterval:   "5 minutes",
+	}
+	if warnings := normalizeLegacyTimSecretSpec(&spec); len(warnings) != 2 {
+		t.Errorf("expected two warnings, got %v", warnings)
+	}
+	if spec.VaultConfig != "" || spec.VaultURL == "" || spec.SyncInterval != "5m" {
+		t.Errorf("unexpected normalized spec %+v", spec)
+	}
+	if errs, _ := validateTimSecretSpec(&spec, field.NewPath("spec")); len(errs) != 0 {
+		t.Errorf("expected the normalized spec to be valid, got %v", errs)
+	}
+
+	// Incomplete direct values used to be ignored
+	spec = secretsv1alpha1.TimSecretSpec{VaultConfig: "vault", VaultURL: "https://vault:8200"}
+	normalizeLegacyTimSecretSpec(&spec)
+	if spec.VaultConfig != "vault" || spec.VaultURL != "" {
+		t.Errorf("unexpected normalized spec %+v", spec)
+	}
+
+	if warnings := normalizeLegacyTimSecretSpec(&secretsv1alpha1.TimSecretSpec{VaultConfig: "vault", SyncInterval: "1m"}); len(warnings) != 0 {
+		t.Errorf("expected no warnings for a valid spec, got %v", warnings)
+	}
+}
+
+func TestSetDeprecatedCondition(t *testing.T) {
+	status := &secretsv1alpha1.TimSecretStatus{}
+	warnings := []string{"spec.syncInterval is not a valid duration, using 5m"}
+
+	// The warnings are announced once, not on every sync
+	if !setDeprecatedCondition(status, warnings) {
+		t.Error("expected new warnings to be reported")
+	}
+	if setDeprecatedCondition(status, warnings) {
+		t.Error("expected unchanged warnings not to be reported again")
+	}
+	if len(status.Conditions) != 1 || status.Conditions[0].Type != "Deprecated" {
+		t.Errorf("unexpected conditions %+v", status.Conditions)
+	}
+
+	if !setDeprecatedCondition(status, nil) || len(status.Conditions) != 0 {
+		t.Errorf("expected the condition to be removed, got %+v", status.Conditions)
+	}
+	if setDeprecatedCondition(status, nil) {
+		t.Error("expected no change without warnings")
+	}
+}
+
+func TestValidateTimSecretDirectorySpec(t *testing.T) {
+	valid := secretsv1alpha1.TimSecretDirectorySpec{
+		VaultConfigRef: &secretsv1alpha1.VaultConfigReference{Name: "vault"},
+		VaultPath:      "secret/data/myapp",
+	}
+	defaultTimSecretDirectory(&valid)
+	if errs, _ := validateTimSecretDirectorySpec(&valid, field.NewPath("spec")); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(spec *secretsv1alpha1.TimSecretDirectorySpec)
+	}{
+		{name: "no configuration", modify: func(spec *secretsv1alpha1.TimSecretDirectorySpec) { spec.VaultConfigRef = nil }},
+		{name: "invalid name template", modify: func(spec *secretsv1alpha1.TimSecretDirectorySpec) { spec.SecretName = "{{ .Path" }},
+		{name: "merge without name", modify: func(spec *secretsv1alpha1.TimSecretDirectorySpec) {
+			spec.Mode = secretsv1alpha1.DirectoryModeMerge
+			spec.SecretName = ""
+		}},
+		{name: "invalid merge name", modify: func(spec *secretsv1alpha1.TimSecretDirectorySpec) {
+			spec.Mode = secretsv1alpha1.DirectoryModeMerge
+			spec.SecretName = "My_Secret"
+		}},
+		{name: "invalid sync interval", modify: func(spec *secretsv1alpha1.TimSecretDirectorySpec) { spec.SyncInterval = "5 minutes" }},
+	}
+
+	for _, tt := range tests {
+		spec := *valid.DeepCopy()
+		tt.modify(&spec)
+		if errs, _ := validateTimSecretDirectorySpec(&spec, field.NewPath("spec")); len(errs) == 0 {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestValidateTimSecretConfigSpec(t *testing.T) {
+	spec := secretsv1alpha1.TimSecretConfigSpec{
+		VaultURL: "https://vault.example.com:8200",
+		Auth: &secretsv1alpha1.VaultAuth{
+			Kubernetes: &secretsv1alpha1.KubernetesAuth{Role: "timvault-operator", ServiceAccountName: "vault-auth"},
+		},
+	}
+	if errs, _ := validateTimSecretConfigSpec(&spec, false, field.NewPath("spec")); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	// Only cluster-scoped configs may use the operator's own ServiceAccount token
+	operatorToken := *spec.DeepCopy()
+	operatorToken.Auth.Kubernetes.ServiceAccountName = ""
+	if errs, _ := validateTimSecretConfigSpec(&operatorToken, false, field.NewPath("spec")); len(errs) != 1 {
+		t.Errorf("expected an error for a namespaced config without serviceAccountName, got %v", errs)
+	}
+	if errs, _ := validateTimSecretConfigSpec(&operatorToken, true, field.NewPath("spec")); len(errs) != 0 {
+		t.Errorf("expected no errors for a cluster-scoped config, got %v", errs)
+	}
+	operatorToken.Auth.Kubernetes = nil
+	operatorToken.Auth.JWT = &secretsv1alpha1.JWTAuth{Role: "timvault-operator"}
+	if errs, _ := validateTimSecretConfigSpec(&operatorToken, false, field.NewPath("spec")); len(errs) != 1 {
+		t.Errorf("expected an error for a namespaced JWT config without serviceAccountName, got %v", errs)
+	}
+
+	spec.TokenSecretRef = &secretsv1alpha1.SecretKeySelector{Name: "vault-token", Key: "token"}
+	spec.Auth.JWT = &secretsv1alpha1.JWTAuth{Role: "timvault-operator", ServiceAccountName: "vault-auth"}
+	if errs, _ := validateTimSecretConfigSpec(&spec, false, field.NewPath("spec")); len(errs) != 2 {
+		t.Errorf("expected errors for auth with a token and two auth methods, got %v", errs)
+	}
+}
@@ -0,0 +1,603 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+	"github.com/renatoruis/timvault-operator/internal/vault"
+)
+
+// serviceAccountTokenPath is where the operator's own projected ServiceAccount token is mounted
+const serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// serviceAccountTokenExpiration is the lifetime requested for ServiceAccount tokens (10 minutes, the API minimum)
+const serviceAccountTokenExpiration int64 = 600
+
+// defaultServiceAccountTokenAudience is the audience of requested ServiceAccount
+// tokens when none is configured. Tokens for the default kube-apiserver
+// audience would let the Vault server, or whoever runs it, call the Kubernetes API.
+const defaultServiceAccountTokenAudience = "vault"
+
+// AllowVaultAuthAnnotation must be set to "true" on a ServiceAccount before
+// TimSecretConfigs may log in to Vault with its tokens
+const AllowVaultAuthAnnotation = "secrets.tim.operator/allow-vault-auth"
+
+// vaultConfig holds the resolved Vault connection settings for a TimSecret
+type vaultConfig struct {
+	url            string
+	token          string
+	vaultNamespace string
+	auth           *secretsv1alpha1.VaultAuth
+	tls            *vault.TLSConfig
+
+	// role overrides the role of the auth method, rendered from auth.roleTemplate
+	role string
+
+	// allowedPaths restricts the Vault paths TimSecrets may read, nil allows every path
+	allowedPaths []secretsv1alpha1.AllowedPathRule
+
+	// namespace is the namespace of the TimSecretConfig, used to resolve
+	// ServiceAccounts and Secrets referenced by the auth method
+	namespace string
+
+	// clusterScoped is set for ClusterTimSecretConfigs, which are created by
+	// cluster administrators. Only they may log in with the operator's own
+	// ServiceAccount token or request tokens for any audience and
+	// ServiceAccount; a namespaced config could otherwise send such tokens to
+	// a Vault URL of its author's choice.
+	clusterScoped bool
+
+	// cacheID and cacheVersion identify the TimSecretConfig in the client cache,
+	// cacheVariant the Vault namespace and role used with it. Only clients using
+	// an auth method are cached; static tokens are read from their Secret on
+	// every reconcile so rotations take effect immediately.
+	cacheID      string
+	cacheVariant string
+	cacheVersion string
+}
+
+// withVaultNamespace returns a copy of the configuration targeting another
+// Vault namespace. Tokens are namespace specific, so the copy gets its own
+// cache entry and logs in to that namespace.
+func (cfg *vaultConfig) withVaultNamespace(vaultNamespace string) *vaultConfig {
+	if vaultNamespace == "" || vaultNamespace == cfg.vaultNamespace {
+		return cfg
+	}
+
+	override := *cfg
+	override.vaultNamespace = vaultNamespace
+	override.cacheVariant += "@" + vaultNamespace
+	return &override
+}
+
+// namespaceTemplateData is the data available to auth.roleTemplate and allowedPaths
+type namespaceTemplateData struct {
+	// Namespace is the namespace of the TimSecret being synced
+	Namespace string
+}
+
+// forTenant returns a copy of the configuration that logs in with the role
+// rendered from auth.roleTemplate for the given TimSecret namespace. Each
+// rendered role gets its own cache entry, and therefore its own token.
+func (cfg *vaultConfig) forTenant(namespace string) (*vaultConfig, error) {
+	if cfg.auth == nil || cfg.auth.RoleTemplate == "" {
+		return cfg, nil
+	}
+	if cfg.auth.AppRole != nil {
+		return nil, fmt.Errorf("auth.roleTemplate is not supported with auth.appRole")
+	}
+
+	role, err := renderRoleTemplate(cfg.auth.RoleTemplate, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := *cfg
+	tenant.role = role
+	tenant.cacheVariant += "#" + role
+	return &tenant, nil
+}
+
+// renderRoleTemplate renders a Vault role name for a TimSecret namespace
+func renderRoleTemplate(roleTemplate, namespace string) (string, error) {
+	role, err := renderNamespaceTemplate("auth.roleTemplate", roleTemplate, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	role = strings.TrimSpace(role)
+	if role == "" {
+		return "", fmt.Errorf("auth.roleTemplate rendered an empty role for namespace %s", namespace)
+	}
+	return role, nil
+}
+
+// renderNamespaceTemplate renders a template field for a TimSecret namespace
+func renderNamespaceTemplate(field, text, namespace string) (string, error) {
+	tmpl, err := template.New(field).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", field, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, namespaceTemplateData{Namespace: namespace}); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", field, err)
+	}
+	return buf.String(), nil
+}
+
+// vaultClientFactory builds Vault clients from TimSecretConfigs,
+// ClusterTimSecretConfigs and TimSecret direct values. It is shared by the
+// TimSecret and config reconcilers so all work with the same cached clients and tokens.
+type vaultClientFactory struct {
+	client.Client
+	clients            *vault.ClientCache
+	rejectInlineTokens bool
+
+	// tokenAudiences are the ServiceAccount token audiences TimSecretConfigs
+	// may request, empty allows only the default audience
+	tokenAudiences []string
+
+	// clusterResourceNamespace is where Secrets, ConfigMaps and ServiceAccounts
+	// referenced by ClusterTimSecretConfigs are looked up
+	clusterResourceNamespace string
+}
+
+// configFromTimSecretConfig resolves the Vault settings of a TimSecretConfig
+func (r *vaultClientFactory) configFromTimSecretConfig(ctx context.Context, config *secretsv1alpha1.TimSecretConfig) (*vaultConfig, error) {
+	return r.configFromSpec(ctx, &config.Spec, config.Namespace,
+		timSecretConfigCacheID(config.Namespace, config.Name),
+		fmt.Sprintf("%s/%d", config.UID, config.Generation),
+		"TimSecretConfig "+config.Namespace+"/"+config.Name)
+}
+
+// configFromClusterTimSecretConfig resolves the Vault settings of a ClusterTimSecretConfig
+func (r *vaultClientFactory) configFromClusterTimSecretConfig(ctx context.Context, config *secretsv1alpha1.ClusterTimSecretConfig) (*vaultConfig, error) {
+	if r.clusterResourceNamespace == "" {
+		return nil, fmt.Errorf("ClusterTimSecretConfig %s cannot be used: no cluster resource namespace configured", config.Name)
+	}
+
+	cfg, err := r.configFromSpec(ctx, &config.Spec.TimSecretConfigSpec, r.clusterResourceNamespace,
+		clusterTimSecretConfigCacheID(config.Name),
+		fmt.Sprintf("%s/%d", config.UID, config.Generation),
+		"ClusterTimSecretConfig "+config.Name)
+	if err != nil {
+		return nil, err
+	}
+	cfg.clusterScoped = true
+	return cfg, nil
+}
+
+// timSecretConfigCacheID identifies a TimSecretConfig in the client cache
+func timSecretConfigCacheID(namespace, name string) string {
+	return fmt.Sprintf("TimSecretConfig/%s/%s", namespace, name)
+}
+
+// clusterTimSecretConfigCacheID identifies a ClusterTimSecretConfig in the client cache
+func clusterTimSecretConfigCacheID(name string) string {
+	return "ClusterTimSecretConfig/" + name
+}
+
+// configFromSpec resolves Vault settings whose referenced Secrets, ConfigMaps
+// and ServiceAccounts live in the given namespace
+func (r *vaultClientFactory) configFromSpec(ctx context.Context, spec *secretsv1alpha1.TimSecretConfigSpec, namespace, cacheID, cacheVersion, owner string) (*vaultConfig, error) {
+	cfg := &vaultConfig{
+		url:            spec.VaultURL,
+		vaultNamespace: spec.VaultNamespace,
+		auth:           spec.Auth,
+		allowedPaths:   spec.AllowedPaths,
+		namespace:      namespace,
+		cacheID:        cacheID,
+		cacheVersion:   cacheVersion,
+	}
+
+	tlsConfig, err := r.resolveTLS(ctx, namespace, spec.TLS, spec.Auth)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		cfg.tls = tlsConfig
+		// Rotated certificates must produce a new client
+		cfg.cacheVersion += "/" + calculateHash(map[string]string{
+			"ca":   string(tlsConfig.CACert),
+			"cert": string(tlsConfig.ClientCert),
+			"key":  string(tlsConfig.ClientKey),
+		})
+	}
+
+	if cfg.auth == nil {
+		if spec.VaultToken == "" && spec.TokenSecretRef == nil {
+			return nil, fmt.Errorf("%s must specify auth, tokenSecretRef or vaultToken", owner)
+		}
+
+		cfg.token, err = r.resolveToken(ctx, spec.VaultToken, spec.TokenSecretRef, namespace, owner)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// resolveTLS loads the CA bundle and client certificate referenced by the TLS
+// settings. With cert auth, the auth method's certificate is the client certificate.
+func (r *vaultClientFactory) resolveTLS(ctx context.Context, namespace string, spec *secretsv1alpha1.VaultTLS, auth *secretsv1alpha1.VaultAuth) (*vault.TLSConfig, error) {
+	certAuth := auth != nil && auth.Cert != nil
+	if spec == nil && !certAuth {
+		return nil, nil
+	}
+	if spec == nil {
+		spec = &secretsv1alpha1.VaultTLS{}
+	}
+
+	tlsConfig := &vault.TLSConfig{
+		ServerName: spec.ServerName,
+		Insecure:   spec.InsecureSkipVerify,
+	}
+
+	if spec.CA != nil {
+		key := spec.CA.Key
+		if key == "" {
+			key = "ca.crt"
+		}
+
+		var ca string
+		var err error
+		switch spec.CA.Kind {
+		case "", "ConfigMap":
+			ca, err = r.configMapValue(ctx, namespace, spec.CA.Name, key)
+		case "Secret":
+			ca, err = r.secretValue(ctx, namespace, spec.CA.Name, key)
+		default:
+			err = fmt.Errorf("unsupported tls.ca.kind %q, must be ConfigMap or Secret", spec.CA.Kind)
+		}
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.CACert = []byte(ca)
+	}
+
+	clientCertSecret := ""
+	switch {
+	case certAuth && spec.ClientCertSecretRef != nil:
+		return nil, fmt.Errorf("tls.clientCertSecretRef cannot be combined with auth.cert, which provides the client certificate")
+	case certAuth:
+		clientCertSecret = auth.Cert.SecretRef.Name
+	case spec.ClientCertSecretRef != nil:
+		clientCertSecret = spec.ClientCertSecretRef.Name
+	}
+
+	if clientCertSecret != "" {
+		cert, key, err := r.tlsKeyPair(ctx, namespace, clientCertSecret)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCert = cert
+		tlsConfig.ClientKey = key
+	}
+
+	return tlsConfig, nil
+}
+
+// tlsKeyPair reads the certificate and key of a kubernetes.io/tls Secret
+func (r *vaultClientFactory) tlsKeyPair(ctx context.Context, namespace, name string) ([]byte, []byte, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret); err != nil {
+		return nil, nil, fmt.Errorf("failed to get Secret %s/%s: %w", namespace, name, err)
+	}
+
+	if secret.Type != corev1.SecretTypeTLS {
+		return nil, nil, fmt.Errorf("Secret %s/%s must be of type %s, got %s", namespace, name, corev1.SecretTypeTLS, secret.Type)
+	}
+
+	cert, key := secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
+	if len(cert) == 0 || len(key) == 0 {
+		return nil, nil, fmt.Errorf("Secret %s/%s must contain %s and %s", namespace, name, corev1.TLSCertKey, corev1.TLSPrivateKeyKey)
+	}
+
+	return cert, key, nil
+}
+
+// resolveToken returns the Vault token from the referenced Secret, falling back
+// to the deprecated inline value unless inline tokens are rejected. The Secret
+// is always read from namespace, the namespace of the referencing resource (or
+// the cluster resource namespace); otherwise anyone able to create a TimSecret
+// could send any Secret the operator can read to a Vault URL of their choice.
+func (r *vaultClientFactory) resolveToken(ctx context.Context, inline string, ref *secretsv1alpha1.SecretKeySelector, namespace, owner string) (string, error) {
+	if ref != nil {
+		if err := checkTokenSecretNamespace(ref, namespace); err != nil {
+			return "", fmt.Errorf("%s: %w", owner, err)
+		}
+		return r.secretValue(ctx, namespace, ref.Name, ref.Key)
+	}
+
+	if r.rejectInlineTokens {
+		return "", fmt.Errorf("%s uses the inline vaultToken field, which is disabled; use tokenSecretRef instead", owner)
+	}
+
+	log.FromContext(ctx).Info("Inline vaultToken is deprecated, use tokenSecretRef instead", "resource", owner)
+	return inline, nil
+}
+
+// checkTokenSecretNamespace refuses token Secret references to a namespace
+// other than the one token Secrets are read from
+func checkTokenSecretNamespace(ref *secretsv1alpha1.SecretKeySelector, namespace string) error {
+	if ref.Namespace != "" && ref.Namespace != namespace {
+		return fmt.Errorf("tokenSecretRef.namespace %s is not allowed, the token Secret must be in namespace %s", ref.Namespace, namespace)
+	}
+	return nil
+}
+
+// newVaultClient returns a Vault client for the resolved configuration,
+// reusing a cached client (and its token) when possible
+func (r *vaultClientFactory) newVaultClient(cfg *vaultConfig) (*vault.Client, error) {
+	create := func() (*vault.Client, error) {
+		clientConfig := vault.Config{Address: cfg.url, Namespace: cfg.vaultNamespace, TLS: cfg.tls}
+		if cfg.auth == nil {
+			return vault.NewClient(clientConfig, cfg.token)
+		}
+
+		authMethod, err := r.vaultAuthMethod(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return vault.NewClientWithAuth(clientConfig, authMethod)
+	}
+
+	if cfg.auth == nil || cfg.cacheID == "" {
+		return create()
+	}
+	return r.clients.Get(cfg.cacheID, cfg.cacheVariant, cfg.cacheVersion, create)
+}
+
+// vaultAuthMethod builds the Vault auth method described by the configuration
+func (r *vaultClientFactory) vaultAuthMethod(cfg *vaultConfig) (vaultapi.AuthMethod, error) {
+	if n := authMethodCount(cfg.auth); n != 1 {
+		return nil, fmt.Errorf("auth must specify exactly one auth method, got %d", n)
+	}
+
+	switch {
+	case cfg.auth.Kubernetes != nil:
+		k8s := cfg.auth.Kubernetes
+		role := k8s.Role
+		if cfg.role != "" {
+			role = cfg.role
+		}
+		if role == "" {
+			return nil, fmt.Errorf("auth.kubernetes.role or auth.roleTemplate must be specified")
+		}
+		tokenSource, err := r.serviceAccountToken(cfg, "auth.kubernetes", k8s.ServiceAccountName, k8s.Audience)
+		if err != nil {
+			return nil, err
+		}
+
+		mountPath := k8s.MountPath
+		if mountPath == "" {
+			mountPath = "kubernetes"
+		}
+
+		return &vault.KubernetesAuth{
+			MountPath:           mountPath,
+			Role:                role,
+			ServiceAccountToken: tokenSource,
+		}, nil
+
+	case cfg.auth.AppRole != nil:
+		appRole := cfg.auth.AppRole
+		if appRole.SecretRef.Name == "" {
+			return nil, fmt.Errorf("auth.appRole.secretRef.name must be specified")
+		}
+
+		mountPath := appRole.MountPath
+		if mountPath == "" {
+			mountPath = "approle"
+		}
+		roleIDKey := appRole.SecretRef.RoleIDKey
+		if roleIDKey == "" {
+			roleIDKey = "role_id"
+		}
+		secretIDKey := appRole.SecretRef.SecretIDKey
+		if secretIDKey == "" {
+			secretIDKey = "secret_id"
+		}
+
+		namespace, name := cfg.namespace, appRole.SecretRef.Name
+		return &vault.AppRoleAuth{
+			MountPath: mountPath,
+			Credentials: func(ctx context.Context) (string, string, error) {
+				roleID, err := r.secretValue(ctx, namespace, name, roleIDKey)
+				if err != nil {
+					return "", "", err
+				}
+				secretID, err := r.secretValue(ctx, namespace, name, secretIDKey)
+				if err != nil {
+					return "", "", err
+				}
+				return roleID, secretID, nil
+			},
+		}, nil
+
+	case cfg.auth.JWT != nil:
+		jwt := cfg.auth.JWT
+		role := jwt.Role
+		if cfg.role != "" {
+			role = cfg.role
+		}
+		if role == "" {
+			return nil, fmt.Errorf("auth.jwt.role or auth.roleTemplate must be specified")
+		}
+		tokenSource, err := r.serviceAccountToken(cfg, "auth.jwt", jwt.ServiceAccountName, jwt.Audience)
+		if err != nil {
+			return nil, err
+		}
+
+		mountPath := jwt.MountPath
+		if mountPath == "" {
+			mountPath = "jwt"
+		}
+
+		return &vault.JWTAuth{
+			MountPath: mountPath,
+			Role:      role,
+			JWT:       tokenSource,
+		}, nil
+
+	case cfg.auth.Cert != nil:
+		if cfg.tls == nil || len(cfg.tls.ClientCert) == 0 {
+			return nil, fmt.Errorf("auth.cert requires a client certificate")
+		}
+
+		mountPath := cfg.auth.Cert.MountPath
+		if mountPath == "" {
+			mountPath = "cert"
+		}
+
+		name := cfg.auth.Cert.Name
+		if cfg.role != "" {
+			name = cfg.role
+		}
+
+		return &vault.CertAuth{
+			MountPath: mountPath,
+			Name:      name,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("auth must specify an auth method")
+}
+
+// authMethodCount returns how many auth methods are configured
+func authMethodCount(auth *secretsv1alpha1.VaultAuth) int {
+	n := 0
+	if auth.Kubernetes != nil {
+		n++
+	}
+	if auth.AppRole != nil {
+		n++
+	}
+	if auth.Cert != nil {
+		n++
+	}
+	if auth.JWT != nil {
+		n++
+	}
+	return n
+}
+
+// serviceAccountToken returns a TokenSource for the given ServiceAccount.
+// Without a name, the operator's own projected token is read from disk, which
+// only ClusterTimSecretConfigs may do; otherwise a short-lived token for the
+// configured audience, "vault" by default, is requested through the TokenRequest API.
+// Namespaced configs may only request allowed audiences, for ServiceAccounts
+// annotated with AllowVaultAuthAnnotation.
+func (r *vaultClientFactory) serviceAccountToken(cfg *vaultConfig, field, name, audience string) (vault.TokenSource, error) {
+	if name == "" {
+		if !cfg.clusterScoped {
+			return nil, fmt.Errorf("%s.serviceAccountName must be specified, only ClusterTimSecretConfigs may use the operator's ServiceAccount token", field)
+		}
+		if audience != "" {
+			return nil, fmt.Errorf("%s.audience requires %s.serviceAccountName", field, field)
+		}
+
+		return func(ctx context.Context) (string, error) {
+			token, err := os.ReadFile(serviceAccountTokenPath)
+			if err != nil {
+				return "", fmt.Errorf("failed to read operator service account token: %w", err)
+			}
+			return strings.TrimSpace(string(token)), nil
+		}, nil
+	}
+
+	if audience == "" {
+		audience = defaultServiceAccountTokenAudience
+	}
+	if !cfg.clusterScoped {
+		if err := checkServiceAccountTokenAudience(audience, r.tokenAudiences); err != nil {
+			return nil, fmt.Errorf("%s.audience: %w", field, err)
+		}
+	}
+
+	namespace, clusterScoped := cfg.namespace, cfg.clusterScoped
+	return func(ctx context.Context) (string, error) {
+		serviceAccount := &corev1.ServiceAccount{}
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, serviceAccount); err != nil {
+			return "", fmt.Errorf("failed to get service account %s/%s: %w", namespace, name, err)
+		}
+		if !clusterScoped && serviceAccount.Annotations[AllowVaultAuthAnnotation] != "true" {
+			return "", fmt.Errorf("service account %s/%s does not allow Vault auth; annotate it with %s=true",
+				namespace, name, AllowVaultAuthAnnotation)
+		}
+
+		expiration := serviceAccountTokenExpiration
+		tokenRequest := &authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{
+				Audiences:         []string{audience},
+				ExpirationSeconds: &expiration,
+			},
+		}
+		if err := r.SubResource("token").Create(ctx, serviceAccount, tokenRequest); err != nil {
+			return "", fmt.Errorf("failed to request token for service account %s/%s: %w", namespace, name, err)
+		}
+
+		return tokenRequest.Status.Token, nil
+	}, nil
+}
+
+// checkServiceAccountTokenAudience returns an error unless the audience is
+// one of the allowed ones, the default audience when none are configured.
+// Audiences the kube-apiserver accepts must never be allowed.
+func checkServiceAccountTokenAudience(audience string, allowed []string) error {
+	if len(allowed) == 0 {
+		allowed = []string{defaultServiceAccountTokenAudience}
+	}
+	for _, a := range allowed {
+		if a == audience {
+			return nil
+		}
+	}
+	return fmt.Errorf("audience %q is not allowed, allowed audiences are %s", audience, strings.Join(allowed, ", "))
+}
+
+// secretValue reads a single key from a Kubernetes Secret
+func (r *vaultClientFactory) secretValue(ctx context.Context, namespace, name, key string) (string, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret); err != nil {
+		return "", fmt.Errorf("failed to get Secret %s/%s: %w", namespace, name, err)
+	}
+
+	value, ok := secret.Data[key]
+	if !ok || len(value) == 0 {
+		return "", fmt.Errorf("key %q not found in Secret %s/%s", key, namespace, name)
+	}
+
+	return string(value), nil
+}
+
+// configMapValue reads a single key from a ConfigMap
+func (r *vaultClientFactory) configMapValue(ctx context.Context, namespace, name, key string) (string, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, configMap); err != nil {
+		return "", fmt.Errorf("failed to get ConfigMap %s/%s: %w", namespace, name, err)
+	}
+
+	if value, ok := configMap.Data[key]; ok && value != "" {
+		return value, nil
+	}
+	if value, ok := configMap.BinaryData[key]; ok && len(value) > 0 {
+		return string(value), nil
+	}
+
+	return "", fmt.Errorf("key %q not found in ConfigMap %s/%s", key, namespace, name)
+}
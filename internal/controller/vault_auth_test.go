@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	secretsv1alpha1 "github.com/renatoruis/timvault-operator/api/v1alpha1"
+	"github.com/renatoruis/timvault-operator/internal/vault"
+)
+
+func TestRenderRoleTemplate(t *testing.T) {
+	role, err := renderRoleTemplate("ns-{{ .Namespace }}", "team-a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if role != "ns-team-a" {
+		t.Errorf("expected role ns-team-a, got %s", role)
+	}
+
+	if _, err := renderRoleTemplate("{{ .Unknown }}", "team-a"); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+
+	if _, err := renderRoleTemplate("  ", "team-a"); err == nil {
+		t.Error("expected an error for an empty role")
+	}
+}
+
+func TestResolveTokenNamespace(t *testing.T) {
+	factory := &vaultClientFactory{}
+	ref := &secretsv1alpha1.SecretKeySelector{Name: "vault-token", Namespace: "kube-system", Key: "token"}
+
+	// Token Secrets outside the referencing resource's namespace are never read
+	if _, err := factory.resolveToken(context.Background(), "", ref, "team-a", "TimSecret team-a/app"); err == nil {
+		t.Error("expected an error for a token Secret in another namespace")
+	}
+
+	for _, namespace := range []string{"", "team-a"} {
+		ref.Namespace = namespace
+		if err := checkTokenSecretNamespace(ref, "team-a"); err != nil {
+			t.Errorf("namespace %q: unexpected error: %v", namespace, err)
+		}
+	}
+}
+
+// tokenRequestFactory returns a factory whose client records the audiences of
+// TokenRequests. The vault-auth ServiceAccount in team-a opted in to Vault auth,
+// the ci ServiceAccount did not.
+func tokenRequestFactory(audiences *[]string) *vaultClientFactory {
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "vault-auth", Namespace: "team-a", Annotations: map[string]string{AllowVaultAuthAnnotation: "true"}}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "ci", Namespace: "team-a"}},
+	).WithInterceptorFuncs(interceptor.Funcs{
+		SubResourceCreate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+			tokenRequest := subResource.(*authenticationv1.TokenRequest)
+			*audiences = tokenRequest.Spec.Audiences
+			tokenRequest.Status.Token = "token"
+			return nil
+		},
+	}).Build()
+	return &vaultClientFactory{Client: c, tokenAudiences: []string{"vault", "vault-prod", "https://vault.example.com"}}
+}
+
+func TestKubernetesAuthServiceAccountToken(t *testing.T) {
+	var audiences []string
+	factory := tokenRequestFactory(&audiences)
+
+	k8s := &secretsv1alpha1.KubernetesAuth{Role: "app", ServiceAccountName: "vault-auth"}
+	cfg := &vaultConfig{namespace: "team-a", auth: &secretsv1alpha1.VaultAuth{Kubernetes: k8s}}
+
+	// Tokens are bound to a Vault audience, never the kube-apiserver's
+	for audience, want := range map[string]string{"": "vault", "vault-prod": "vault-prod"} {
+		k8s.Audience = audience
+		method, err := factory.vaultAuthMethod(cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := method.(*vault.KubernetesAuth).ServiceAccountToken(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(audiences) != 1 || audiences[0] != want {
+			t.Errorf("audience %q: expected TokenRequest audiences [%s], got %v", audience, want, audiences)
+		}
+	}
+
+	// Namespaced configs cannot mint tokens the kube-apiserver accepts
+	k8s.Audience = "https://kubernetes.default.svc.cluster.local"
+	if _, err := factory.vaultAuthMethod(cfg); err == nil {
+		t.Error("expected an error for a kube-apiserver audience")
+	}
+
+	// ServiceAccounts that did not opt in are never used
+	k8s.ServiceAccountName, k8s.Audience = "ci", ""
+	method, err := factory.vaultAuthMethod(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	audiences = nil
+	if _, err := method.(*vault.KubernetesAuth).ServiceAccountToken(context.Background()); err == nil {
+		t.Error("expected an error for a ServiceAccount without the opt-in annotation")
+	}
+	if audiences != nil {
+		t.Errorf("expected no TokenRequest, got audiences %v", audiences)
+	}
+
+	// Only cluster-scoped configs may fall back to the operator's own token
+	k8s.ServiceAccountName = ""
+	if _, err := factory.vaultAuthMethod(cfg); err == nil {
+		t.Error("expected an error for a namespaced config without serviceAccountName")
+	}
+	cfg.clusterScoped = true
+	if _, err := factory.vaultAuthMethod(cfg); err != nil {
+		t.Errorf("unexpected error for a cluster-scoped config: %v", err)
+	}
+}
+
+func TestCheckServiceAccountTokenAudience(t *testing.T) {
+	if err := checkServiceAccountTokenAudience("vault", nil); err != nil {
+		t.Errorf("unexpected error for the default audience: %v", err)
+	}
+	for _, audience := range []string{"https://kubernetes.default.svc.cluster.local", "api", ""} {
+		if err := checkServiceAccountTokenAudience(audience, nil); err == nil {
+			t.Errorf("audience %q: expected an error", audience)
+		}
+	}
+	if err := checkServiceAccountTokenAudience("vault-prod", []string{"vault-prod"}); err != nil {
+		t.Errorf("unexpected error for an allowed audience: %v", err)
+	}
+}
+
+func TestJWTAuthServiceAccountToken(t *testing.T) {
+	var audiences []string
+	factory := tokenRequestFactory(&audiences)
+
+	jwt := &secretsv1alpha1.JWTAuth{Role: "app", ServiceAccountName: "vault-auth"}
+	cfg := &vaultConfig{namespace: "team-a", auth: &secretsv1alpha1.VaultAuth{JWT: jwt}}
+
+	// Roles binding on aud only match with a Vault audience
+	for audience, want := range map[string]string{"": "vault", "https://vault.example.com": "https://vault.example.com"} {
+		jwt.Audience = audience
+		method, err := factory.vaultAuthMethod(cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := method.(*vault.JWTAuth).JWT(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(audiences) != 1 || audiences[0] != want {
+			t.Errorf("audience %q: expected TokenRequest audiences [%s], got %v", audience, want, audiences)
+		}
+	}
+
+	jwt.Audience = "https://kubernetes.default.svc.cluster.local"
+	if _, err := factory.vaultAuthMethod(cfg); err == nil {
+		t.Error("expected an error for a kube-apiserver audience")
+	}
+
+	jwt.ServiceAccountName, jwt.Audience = "ci", ""
+	method, err := factory.vaultAuthMethod(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	audiences = nil
+	if _, err := method.(*vault.JWTAuth).JWT(context.Background()); err == nil {
+		t.Error("expected an error for a ServiceAccount without the opt-in annotation")
+	}
+	if audiences != nil {
+		t.Errorf("expected no TokenRequest, got audiences %v", audiences)
+	}
+
+	jwt.ServiceAccountName = ""
+	if _, err := factory.vaultAuthMethod(cfg); err == nil {
+		t.Error("expected an error for a namespaced config without serviceAccountName")
+	}
+	cfg.clusterScoped = true
+	if _, err := factory.vaultAuthMethod(cfg); err != nil {
+		t.Errorf("unexpected error for a cluster-scoped config: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// TokenSource returns a credential presented to Vault during login
+type TokenSource func(ctx context.Context) (string, error)
+
+// KubernetesAuth logs in using Vault's kubernetes auth method
+type KubernetesAuth struct {
+	// MountPath is the path where the auth method is mounted (e.g. "kubernetes")
+	MountPath string
+
+	// Role is the Vault role to log in with
+	Role string
+
+	// ServiceAccountToken returns the ServiceAccount JWT sent to Vault
+	ServiceAccountToken TokenSource
+}
+
+// Login implements vault.AuthMethod
+func (a *KubernetesAuth) Login(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	jwt, err := a.ServiceAccountToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service account token: %w", err)
+	}
+
+	return login(ctx, client, a.MountPath, map[string]interface{}{
+		"role": a.Role,
+		"jwt":  jwt,
+	})
+}
+
+// JWTAuth logs in using Vault's jwt auth method
+type JWTAuth struct {
+	// MountPath is the path where the auth method is mounted (e.g. "jwt")
+	MountPath string
+
+	// Role is the Vault role to log in with
+	Role string
+
+	// JWT returns the token sent to Vault
+	JWT TokenSource
+}
+
+// Login implements vault.AuthMethod
+func (a *JWTAuth) Login(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	jwt, err := a.JWT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jwt: %w", err)
+	}
+
+	return login(ctx, client, a.MountPath, map[string]interface{}{
+		"role": a.Role,
+		"jwt":  jwt,
+	})
+}
+
+// AppRoleAuth logs in using Vault's approle auth method
+type AppRoleAuth struct {
+	// MountPath is the path where the auth method is mounted (e.g. "approle")
+	MountPath string
+
+	// Credentials returns the role_id and secret_id. It is called on every
+	// login so rotated secret_ids are picked up.
+	Credentials func(ctx context.Context) (roleID, secretID string, err error)
+}
+
+// Login implements vault.AuthMethod
+func (a *AppRoleAuth) Login(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	roleID, secretID, err := a.Credentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get approle credentials: %w", err)
+	}
+
+	return login(ctx, client, a.MountPath, map[string]interface{}{
+		"role_id":   roleID,
+		"secret_id": secretID,
+	})
+}
+
+// CertAuth logs in using Vault's cert auth method. The client certificate
+// itself is presented during the TLS handshake, see TLSConfig.
+type CertAuth struct {
+	// MountPath is the path where the auth method is mounted (e.g. "cert")
+	MountPath string
+
+	// Name is the certificate role to log in with, empty tries every matching role
+	Name string
+}
+
+// Login implements vault.AuthMethod
+func (a *CertAuth) Login(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	data := map[string]interface{}{}
+	if a.Name != "" {
+		data["name"] = a.Name
+	}
+
+	return login(ctx, client, a.MountPath, data)
+}
+
+// login writes the credentials to auth/<mountPath>/login and validates the response
+func login(ctx context.Context, client *vault.Client, mountPath string, data map[string]interface{}) (*vault.Secret, error) {
+	path := fmt.Sprintf("auth/%s/login", strings.Trim(mountPath, "/"))
+
+	secret, err := client.Logical().WriteWithContext(ctx, path, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log in to %s: %w", path, err)
+	}
+
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return nil, fmt.Errorf("no client token returned by %s", path)
+	}
+
+	return secret, nil
+}
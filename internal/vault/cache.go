@@ -0,0 +1,105 @@
+package vault
+
+import (
+	"sync"
+)
+
+// ClientCache keeps Vault clients between reconciles so that tokens obtained
+// through an auth method are reused instead of logging in on every sync.
+// Clients are grouped by the configuration they were built from; a
+// configuration can have several variants (e.g. one per Vault namespace or
+// role), all dropped together when it changes or is removed.
+type ClientCache struct {
+	mu      sync.Mutex
+	configs map[string]*configEntry
+}
+
+// configEntry holds the clients of one version of a configuration
+type configEntry struct {
+	version string
+	clients map[string]*cacheEntry
+}
+
+// cacheEntry is a client that is being or has been created. done is closed
+// once client or err is set.
+type cacheEntry struct {
+	done   chan struct{}
+	client *Client
+	err    error
+}
+
+// NewClientCache creates an empty client cache
+func NewClientCache() *ClientCache {
+	return &ClientCache{configs: make(map[string]*configEntry)}
+}
+
+// Get returns the cached client for a variant of a configuration, calling
+// create when there is no client yet or when the cached clients were built
+// from a different version of the configuration. create runs without holding
+// the cache lock, so a slow Vault only delays callers of the same client;
+// concurrent callers of that client wait for the one create call.
+func (c *ClientCache) Get(config, variant, version string, create func() (*Client, error)) (*Client, error) {
+	c.mu.Lock()
+	group, ok := c.configs[config]
+	if !ok || group.version != version {
+		// Clients of an older version are dropped along with their tokens
+		group = &configEntry{version: version, clients: make(map[string]*cacheEntry)}
+		c.configs[config] = group
+	}
+
+	if entry, ok := group.clients[variant]; ok {
+		c.mu.Unlock()
+		<-entry.done
+		return entry.client, entry.err
+	}
+
+	entry := &cacheEntry{done: make(chan struct{})}
+	group.clients[variant] = entry
+	c.mu.Unlock()
+
+	entry.client, entry.err = create()
+	if entry.err != nil {
+		// Failures are not cached, the next call tries again
+		c.mu.Lock()
+		if group.clients[variant] == entry {
+			delete(group.clients, variant)
+		}
+		c.mu.Unlock()
+	}
+	close(entry.done)
+
+	return entry.client, entry.err
+}
+
+// Clients returns the clients created so far for a version of a
+// configuration, e.g. to keep the tokens of all its variants alive. Clients
+// still being created are skipped.
+func (c *ClientCache) Clients(config, version string) []*Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, ok := c.configs[config]
+	if !ok || group.version != version {
+		return nil
+	}
+
+	var clients []*Client
+	for _, entry := range group.clients {
+		select {
+		case <-entry.done:
+			if entry.client != nil {
+				clients = append(clients, entry.client)
+			}
+		default:
+		}
+	}
+	return clients
+}
+
+// Remove drops all clients of a configuration, e.g. when it was deleted
+func (c *ClientCache) Remove(config string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.configs, config)
+}
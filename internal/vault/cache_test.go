@@ -0,0 +1,77 @@
+package vault
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientCacheGet(t *testing.T) {
+	cache := NewClientCache()
+	var created int32
+	create := func() (*Client, error) {
+		atomic.AddInt32(&created, 1)
+		time.Sleep(10 * time.Millisecond)
+		return &Client{}, nil
+	}
+
+	// Concurrent callers of one client share a single create call
+	var wg sync.WaitGroup
+	clients := make([]*Client, 10)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			clients[i], _ = cache.Get("TimSecretConfig/ns/vault", "", "1", create)
+		}(i)
+	}
+	wg.Wait()
+	if created != 1 {
+		t.Fatalf("expected one client to be created, got %d", created)
+	}
+	for _, client := range clients {
+		if client != clients[0] {
+			t.Fatal("expected all callers to get the same client")
+		}
+	}
+
+	// Another variant gets its own client, a new version replaces all of them
+	other, _ := cache.Get("TimSecretConfig/ns/vault", "#role", "1", create)
+	if other == clients[0] {
+		t.Error("expected a separate client for another variant")
+	}
+	if client, _ := cache.Get("TimSecretConfig/ns/vault", "", "2", create); client == clients[0] {
+		t.Error("expected a new client for a new version")
+	}
+	if n := len(cache.configs["TimSecretConfig/ns/vault"].clients); n != 1 {
+		t.Errorf("expected the clients of the old version to be dropped, got %d clients", n)
+	}
+
+	if n := len(cache.Clients("TimSecretConfig/ns/vault", "2")); n != 1 {
+		t.Errorf("expected one client of the current version, got %d", n)
+	}
+	if n := len(cache.Clients("TimSecretConfig/ns/vault", "1")); n != 0 {
+		t.Errorf("expected no clients of an old version, got %d", n)
+	}
+
+	cache.Remove("TimSecretConfig/ns/vault")
+	if len(cache.configs) != 0 {
+		t.Error("expected the configuration to be removed")
+	}
+}
+
+func TestClientCacheGetError(t *testing.T) {
+	cache := NewClientCache()
+	failing := func() (*Client, error) { return nil, errors.New("unreachable") }
+	if _, err := cache.Get("config", "", "1", failing); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// Errors are not cached
+	client, err := cache.Get("config", "", "1", func() (*Client, error) { return &Client{}, nil })
+	if err != nil || client == nil {
+		t.Errorf("expected a client after a failure, got %v, %v", client, err)
+	}
+}
@@ -2,34 +2,300 @@ package vault
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	vault "github.com/hashicorp/vault/api"
 )
 
+// tokenExpiryMargin is how long before the token expires a new login is performed
+const tokenExpiryMargin = 30 * time.Second
+
 // Client wraps the Vault API client
 type Client struct {
 	client *vault.Client
+
+	// auth is nil when the client uses a static token
+	auth vault.AuthMethod
+
+	mu          sync.Mutex
+	loggedIn    bool
+	tokenExpiry time.Time
+
+	// mounts caches the mounts of the paths read, by mount path
+	mounts map[string]*Mount
+
+	// mountErrors caches failed mount lookups, by path
+	mountErrors map[string]*mountLookupError
+}
+
+// Config holds the connection settings of a Vault client
+type Config struct {
+	// Address is the Vault server URL
+	Address string
+
+	// Namespace is the Vault Enterprise namespace sent with every request,
+	// including logins
+	Namespace string
+
+	// TLS configures the TLS connection, nil uses the defaults
+	TLS *TLSConfig
+}
+
+// TLSConfig holds the TLS settings of a Vault client
+type TLSConfig struct {
+	// CACert is a PEM encoded CA bundle used to verify the server
+	CACert []byte
+
+	// ClientCert and ClientKey are the PEM encoded client certificate and key
+	ClientCert []byte
+	ClientKey  []byte
+
+	// ServerName is used for SNI and server certificate verification
+	ServerName string
+
+	// Insecure disables server certificate verification
+	Insecure bool
 }
 
 // NewClient creates a new Vault client
-func NewClient(address, token string) (*Client, error) {
+func NewClient(cfg Config, token string) (*Client, error) {
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client.SetToken(token)
+
+	return &Client{client: client}, nil
+}
+
+// NewClientWithAuth creates a new Vault client that obtains its token by
+// logging in with the given auth method
+func NewClientWithAuth(cfg Config, auth vault.AuthMethod) (*Client, error) {
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Never fall back to a token from the operator's environment
+	client.ClearToken()
+
+	return &Client{client: client, auth: auth}, nil
+}
+
+// newAPIClient creates the underlying Vault API client
+func newAPIClient(cfg Config) (*vault.Client, error) {
 	config := vault.DefaultConfig()
-	config.Address = address
+	config.Address = cfg.Address
+
+	if cfg.TLS != nil {
+		if err := configureTLS(config, cfg.TLS); err != nil {
+			return nil, err
+		}
+	}
 
 	client, err := vault.NewClient(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create vault client: %w", err)
 	}
 
-	client.SetToken(token)
+	// Never fall back to VAULT_NAMESPACE from the operator's environment
+	if cfg.Namespace != "" {
+		client.SetNamespace(cfg.Namespace)
+	} else {
+		client.ClearNamespace()
+	}
 
-	return &Client{client: client}, nil
+	return client, nil
+}
+
+// configureTLS applies the TLS settings to the Vault API configuration
+func configureTLS(config *vault.Config, t *TLSConfig) error {
+	err := config.ConfigureTLS(&vault.TLSConfig{
+		CACertBytes:   t.CACert,
+		TLSServerName: t.ServerName,
+		Insecure:      t.Insecure,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to configure vault TLS: %w", err)
+	}
+
+	if len(t.ClientCert) == 0 && len(t.ClientKey) == 0 {
+		return nil
+	}
+
+	cert, err := tls.X509KeyPair(t.ClientCert, t.ClientKey)
+	if err != nil {
+		return fmt.Errorf("failed to load vault client certificate: %w", err)
+	}
+
+	transport, ok := config.HttpClient.Transport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("unsupported vault HTTP transport %T", config.HttpClient.Transport)
+	}
+	// Always present the certificate, regardless of the CAs the server asks
+	// for, as Vault's cert auth method may trust CAs outside its TLS CA pool
+	transport.TLSClientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return &cert, nil
+	}
+
+	return nil
+}
+
+// Authenticate logs in to Vault if the client has no valid token yet.
+// The resulting token is cached until shortly before it expires.
+func (c *Client) Authenticate(ctx context.Context) error {
+	if c.auth == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.loggedIn && (c.tokenExpiry.IsZero() || time.Now().Before(c.tokenExpiry.Add(-tokenExpiryMargin))) {
+		return nil
+	}
+
+	_, err := c.loginLocked(ctx)
+	return err
+}
+
+// loginLocked performs a login with the client's auth method. c.mu must be held.
+func (c *Client) loginLocked(ctx context.Context) (*vault.Secret, error) {
+	secret, err := c.client.Auth().Login(ctx, c.auth)
+	if err != nil {
+		c.loggedIn = false
+		return nil, fmt.Errorf("failed to authenticate to vault: %w", err)
+	}
+
+	c.loggedIn = true
+	c.setTokenTTLLocked(time.Duration(secret.Auth.LeaseDuration) * time.Second)
+
+	return secret, nil
+}
+
+// setTokenTTLLocked records when the current token expires. c.mu must be held.
+func (c *Client) setTokenTTLLocked(ttl time.Duration) {
+	c.tokenExpiry = time.Time{}
+	if ttl > 0 {
+		c.tokenExpiry = time.Now().Add(ttl)
+	}
+}
+
+// invalidateToken forces a new login on the next Authenticate call
+func (c *Client) invalidateToken() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.loggedIn = false
+}
+
+// read reads a path from Vault. If the client uses an auth method and Vault
+// rejects the token (e.g. it was revoked or expired early), the client logs
+// in again and retries once.
+func (c *Client) read(ctx context.Context, path string, query map[string][]string) (*vault.Secret, error) {
+	return c.withReauth(ctx, func() (*vault.Secret, error) {
+		return c.client.Logical().ReadWithDataWithContext(ctx, path, query)
+	})
+}
+
+// list lists a path in Vault, logging in again like read
+func (c *Client) list(ctx context.Context, path string) (*vault.Secret, error) {
+	return c.withReauth(ctx, func() (*vault.Secret, error) {
+		return c.client.Logical().ListWithContext(ctx, path)
+	})
+}
+
+// withReauth performs a request, logging in again and retrying once if the
+// client uses an auth method and Vault rejects the token
+func (c *Client) withReauth(ctx context.Context, request func() (*vault.Secret, error)) (*vault.Secret, error) {
+	if err := c.Authenticate(ctx); err != nil {
+		return nil, err
+	}
+
+	secret, err := request()
+
+	var respErr *vault.ResponseError
+	if c.auth != nil && errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden {
+		c.invalidateToken()
+		if err := c.Authenticate(ctx); err != nil {
+			return nil, err
+		}
+		secret, err = request()
+	}
+
+	return secret, err
+}
+
+// Secret is a secret read from a KV engine
+type Secret struct {
+	// Path is the path the secret was read from
+	Path string
+
+	// Data holds the secret's keys and values
+	Data map[string]string
+
+	// KVVersion is the version of the KV engine, 1 or 2
+	KVVersion int
+
+	// Version is the version of the secret read, only set by KV v2
+	Version int
+
+	// CurrentVersion is the latest version of the secret, only set by KV v2.
+	// It differs from Version when an older version was requested.
+	CurrentVersion int
+
+	// CustomMetadata is the custom metadata of the secret, only set by KV v2
+	CustomMetadata map[string]string
 }
 
 // GetSecrets retrieves secrets from the specified path in Vault
 func (c *Client) GetSecrets(ctx context.Context, path string) (map[string]string, error) {
-	secret, err := c.client.Logical().ReadWithContext(ctx, path)
+	secret, err := c.ReadSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return secret.Data, nil
+}
+
+// ReadSecret retrieves the latest version of a secret from the specified path in Vault
+func (c *Client) ReadSecret(ctx context.Context, path string) (*Secret, error) {
+	return c.ReadSecretVersion(ctx, path, 0)
+}
+
+// ReadSecretVersion retrieves a secret and its version from the specified
+// path in Vault. The KV version of the path's mount is looked up, so KV v2
+// secrets can be given by their logical path (mount/name) or their API path
+// (mount/data/name). A version above zero reads that version of a KV v2
+// secret. Paths whose mount cannot be looked up return ErrMountLookup.
+func (c *Client) ReadSecretVersion(ctx context.Context, path string, version int) (*Secret, error) {
+	mount, err := c.LookupMount(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	apiPath := path
+	var query map[string][]string
+	if mount.KVVersion == 2 {
+		apiPath = mount.dataPath(path)
+	}
+	if version > 0 {
+		if mount.KVVersion != 2 {
+			return nil, fmt.Errorf("version %d of %s requested, but versions are only supported by KV v2 and %s is KV v%d", version, path, mount.Path, mount.KVVersion)
+		}
+		query = map[string][]string{"version": {strconv.Itoa(version)}}
+	}
+
+	secret, err := c.read(ctx, apiPath, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read secret from vault: %w", err)
 	}
@@ -38,25 +304,143 @@ func (c *Client) GetSecrets(ctx context.Context, path string) (map[string]string
 		return nil, fmt.Errorf("secret not found at path: %s", path)
 	}
 
+	result := &Secret{Path: path, KVVersion: mount.KVVersion}
+
 	// Handle both KV v1 and KV v2
 	var data map[string]interface{}
-	if secret.Data["data"] != nil {
-		// KV v2
-		data = secret.Data["data"].(map[string]interface{})
+	if result.KVVersion == 2 {
+		metadata, _ := secret.Data["metadata"].(map[string]interface{})
+		result.Version = intValue(metadata["version"])
+		result.CurrentVersion = result.Version
+		if custom, ok := metadata["custom_metadata"].(map[string]interface{}); ok {
+			result.CustomMetadata = make(map[string]string, len(custom))
+			for k, v := range custom {
+				result.CustomMetadata[k] = stringValue(v)
+			}
+		}
+
+		var ok bool
+		if data, ok = secret.Data["data"].(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("version %d of secret at path %s is deleted or destroyed", result.Version, path)
+		}
+
+		// The latest version is only known from the metadata, which the
+		// token may not be allowed to read; it is then left unset
+		if version > 0 {
+			result.CurrentVersion = 0
+			if meta, err := c.read(ctx, mount.metadataPath(path), nil); err == nil && meta != nil {
+				result.CurrentVersion = intValue(meta.Data["current_version"])
+			}
+		}
 	} else {
 		// KV v1
 		data = secret.Data
 	}
 
 	// Convert to map[string]string
-	result := make(map[string]string)
+	result.Data = make(map[string]string)
 	for k, v := range data {
-		if str, ok := v.(string); ok {
-			result[k] = str
-		} else {
-			result[k] = fmt.Sprintf("%v", v)
-		}
+		result.Data[k] = stringValue(v)
 	}
 
 	return result, nil
 }
+
+// stringValue converts a value decoded from a Vault response to a string.
+// Objects and lists are encoded as JSON so their fields can be selected.
+func stringValue(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case map[string]interface{}, []interface{}:
+		if encoded, err := json.Marshal(value); err == nil {
+			return string(encoded)
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// ErrTooManySecrets is returned by ListSecrets when a path holds more secrets than allowed
+var ErrTooManySecrets = errors.New("too many secrets")
+
+// ListSecrets returns the paths of all secrets below path, recursively,
+// relative to path and sorted. KV v2 prefixes are listed through the engine's
+// metadata path and, like reads, may be logical paths (mount/prefix) or data
+// paths (mount/data/prefix). More than limit secrets return ErrTooManySecrets,
+// and paths whose mount cannot be looked up return ErrMountLookup.
+func (c *Client) ListSecrets(ctx context.Context, path string, limit int) ([]string, error) {
+	root := strings.Trim(path, "/")
+	mount, err := c.LookupMount(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	if mount.KVVersion == 2 {
+		root = mount.metadataPath(root)
+	}
+
+	keys, err := c.listKeys(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	var leaves []string
+	dir := ""
+	var pending []string
+	for {
+		for _, key := range keys {
+			if strings.HasSuffix(key, "/") {
+				pending = append(pending, dir+key)
+				continue
+			}
+			leaves = append(leaves, dir+key)
+			if len(leaves) > limit {
+				return nil, fmt.Errorf("%w: more than %d below %s", ErrTooManySecrets, limit, path)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+
+		dir, pending = pending[0], pending[1:]
+		if keys, err = c.listKeys(ctx, root+"/"+strings.TrimSuffix(dir, "/")); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(leaves)
+	return leaves, nil
+}
+
+// listKeys returns the keys listed at a path, nil if there are none
+func (c *Client) listKeys(ctx context.Context, path string) ([]string, error) {
+	secret, err := c.list(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets at %s: %w", path, err)
+	}
+	if secret == nil {
+		return nil, nil
+	}
+
+	raw, _ := secret.Data["keys"].([]interface{})
+	keys := make([]string, 0, len(raw))
+	for _, key := range raw {
+		if str, ok := key.(string); ok {
+			keys = append(keys, str)
+		}
+	}
+	return keys, nil
+}
+
+// intValue converts a number decoded from a Vault response to an int
+func intValue(v interface{}) int {
+	switch n := v.(type) {
+	case json.Number:
+		i, _ := n.Int64()
+		return int(i)
+	case float64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
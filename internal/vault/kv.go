@@ -0,0 +1,161 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// mountCacheTTL is how long the KV version of a mount is remembered, so a
+// KV v1 mount upgraded to v2 is picked up without restarting the operator.
+// Lookups Vault refused for good, because a fresh token may not read the path
+// or no mount serves it, are remembered as long so they are not retried on
+// every read. Transport errors, server errors and failed logins are not.
+const mountCacheTTL = 10 * time.Minute
+
+// ErrMountLookup is returned by reads and listings when the mount of a path
+// cannot be looked up, e.g. because the token may not read it
+var ErrMountLookup = errors.New("failed to look up the mount")
+
+// Mount is a secrets engine mount
+type Mount struct {
+	// Path is the mount path with a trailing slash, e.g. secret/
+	Path string
+
+	// KVVersion is the version of the KV engine, 1 or 2. Paths of other
+	// engines are read as they are, like KV v1.
+	KVVersion int
+
+	checked time.Time
+}
+
+// mountLookupError is a failed mount lookup, remembered by path
+type mountLookupError struct {
+	err     error
+	checked time.Time
+}
+
+// LookupMount returns the mount a path belongs to, asking Vault through
+// sys/internal/ui/mounts the first time a mount is used. Mounts cannot be
+// nested, so a cached mount that prefixes the path is the path's mount. Errors
+// wrap ErrMountLookup.
+func (c *Client) LookupMount(ctx context.Context, path string) (*Mount, error) {
+	path = strings.Trim(path, "/")
+
+	c.mu.Lock()
+	for _, mount := range c.mounts {
+		if strings.HasPrefix(path+"/", mount.Path) && time.Since(mount.checked) < mountCacheTTL {
+			c.mu.Unlock()
+			return mount, nil
+		}
+	}
+	if failed, ok := c.mountErrors[path]; ok && time.Since(failed.checked) < mountCacheTTL {
+		c.mu.Unlock()
+		return nil, failed.err
+	}
+	c.mu.Unlock()
+
+	mount, authoritative, err := c.readMount(ctx, path)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		err = fmt.Errorf("%w of %s: %w", ErrMountLookup, path, err)
+		if authoritative {
+			if c.mountErrors == nil {
+				c.mountErrors = map[string]*mountLookupError{}
+			}
+			c.mountErrors[path] = &mountLookupError{err: err, checked: time.Now()}
+		}
+		return nil, err
+	}
+	if c.mounts == nil {
+		c.mounts = map[string]*Mount{}
+	}
+	c.mounts[mount.Path] = mount
+	delete(c.mountErrors, path)
+	return mount, nil
+}
+
+// readMount asks Vault for the mount of a path. Like secret reads, a refused
+// request logs in again and is retried once, as the token may have been
+// revoked. The returned flag reports whether an error is Vault's final answer
+// for the path (permission denied with a fresh token, or no mount), rather
+// than a failed login or a transient failure.
+func (c *Client) readMount(ctx context.Context, path string) (*Mount, bool, error) {
+	var requestErr error
+	secret, err := c.withReauth(ctx, func() (*vault.Secret, error) {
+		secret, err := c.client.Logical().ReadWithContext(ctx, "sys/internal/ui/mounts/"+path)
+		requestErr = err
+		return secret, err
+	})
+	if err != nil {
+		var respErr *vault.ResponseError
+		authoritative := err == requestErr && errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden
+		return nil, authoritative, err
+	}
+	if secret == nil {
+		return nil, true, errors.New("no mount found")
+	}
+	mount := mountFromData(secret.Data)
+	if mount.Path == "" || !strings.HasPrefix(path+"/", mount.Path) {
+		return nil, true, fmt.Errorf("unexpected mount %q", mount.Path)
+	}
+	mount.checked = time.Now()
+	return mount, false, nil
+}
+
+// mountFromData parses a sys/internal/ui/mounts response
+func mountFromData(data map[string]interface{}) *Mount {
+	mount := &Mount{KVVersion: 1}
+	if path, ok := data["path"].(string); ok && path != "" {
+		mount.Path = strings.TrimPrefix(strings.TrimSuffix(path, "/")+"/", "/")
+	}
+
+	if options, ok := data["options"].(map[string]interface{}); ok && data["type"] == "kv" && stringValue(options["version"]) == "2" {
+		mount.KVVersion = 2
+	}
+	return mount
+}
+
+// dataPath returns the API path reading a secret of a KV v2 mount. Logical
+// paths (mount/name) get the data/ segment added; paths that already have
+// it (mount/data/name) are used unchanged, and metadata paths
+// (mount/metadata/name) read the secret they describe.
+func (m *Mount) dataPath(path string) string {
+	return strings.TrimSuffix(m.Path+"data/"+m.relativePath(path), "/")
+}
+
+// metadataPath returns the API path of a secret's metadata on a KV v2 mount,
+// which is also the path secrets are listed at
+func (m *Mount) metadataPath(path string) string {
+	return strings.TrimSuffix(m.Path+"metadata/"+m.relativePath(path), "/")
+}
+
+// PolicyPath returns a path of the mount in the form Vault policies use for
+// reading it: the data path (mount/data/name) on KV v2, the path itself
+// otherwise
+func (m *Mount) PolicyPath(path string) string {
+	if m.KVVersion != 2 || !strings.HasPrefix(strings.Trim(path, "/")+"/", m.Path) {
+		return path
+	}
+	return m.dataPath(path)
+}
+
+// relativePath returns the secret's name within a KV v2 mount, given as its
+// logical, data or metadata path
+func (m *Mount) relativePath(path string) string {
+	name := strings.TrimPrefix(strings.Trim(path, "/")+"/", m.Path)
+	for _, prefix := range []string{"data/", "metadata/"} {
+		if strings.HasPrefix(name, prefix) {
+			name = strings.TrimPrefix(name, prefix)
+			break
+		}
+	}
+	return strings.TrimSuffix(name, "/")
+}
@@ -0,0 +1,239 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+func TestMountFromData(t *testing.T) {
+	tests := []struct {
+		data map[string]interface{}
+		want Mount
+	}{
+		{map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": "2"}}, Mount{Path: "secret/", KVVersion: 2}},
+		{map[string]interface{}{"path": "kv", "type": "kv", "options": map[string]interface{}{"version": "1"}}, Mount{Path: "kv/", KVVersion: 1}},
+		{map[string]interface{}{"path": "legacy/", "type": "generic", "options": nil}, Mount{Path: "legacy/", KVVersion: 1}},
+		{map[string]interface{}{"path": "database/", "type": "database"}, Mount{Path: "database/", KVVersion: 1}},
+	}
+	for _, tt := range tests {
+		if got := mountFromData(tt.data); *got != tt.want {
+			t.Errorf("%v: got %+v, want %+v", tt.data, *got, tt.want)
+		}
+	}
+}
+
+func TestKVv2Paths(t *testing.T) {
+	mount := &Mount{Path: "secret/", KVVersion: 2}
+	tests := []struct {
+		path, data, metadata string
+	}{
+		{"secret/myapp/db", "secret/data/myapp/db", "secret/metadata/myapp/db"},
+		{"secret/data/myapp/db", "secret/data/myapp/db", "secret/metadata/myapp/db"},
+		{"secret/metadata/myapp/db", "secret/data/myapp/db", "secret/metadata/myapp/db"},
+		{"/secret/myapp/", "secret/data/myapp", "secret/metadata/myapp"},
+		{"secret", "secret/data", "secret/metadata"},
+	}
+	for _, tt := range tests {
+		if got := mount.dataPath(tt.path); got != tt.data {
+			t.Errorf("dataPath(%q) = %q, want %q", tt.path, got, tt.data)
+		}
+		if got := mount.metadataPath(tt.path); got != tt.metadata {
+			t.Errorf("metadataPath(%q) = %q, want %q", tt.path, got, tt.metadata)
+		}
+	}
+}
+
+// countingAuth is an auth method that counts its logins
+type countingAuth struct{ logins int }
+
+func (a *countingAuth) Login(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	a.logins++
+	return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "token", LeaseDuration: 3600}}, nil
+}
+
+func TestPolicyPath(t *testing.T) {
+	v2 := &Mount{Path: "secret/", KVVersion: 2}
+	v1 := &Mount{Path: "kv/", KVVersion: 1}
+	tests := []struct {
+		mount      *Mount
+		path, want string
+	}{
+		{v2, "secret/team-a/db", "secret/data/team-a/db"},
+		{v2, "secret/data/team-a/db", "secret/data/team-a/db"},
+		{v2, "secret/metadata/team-a/db", "secret/data/team-a/db"},
+		{v2, "secret/team-a/*", "secret/data/team-a/*"},
+		{v2, "secret/*", "secret/data/*"},
+		{v2, "other/team-a/db", "other/team-a/db"},
+		{v1, "kv/data/team-a", "kv/data/team-a"},
+	}
+	for _, tt := range tests {
+		if got := tt.mount.PolicyPath(tt.path); got != tt.want {
+			t.Errorf("PolicyPath(%q) on %s = %q, want %q", tt.path, tt.mount.Path, got, tt.want)
+		}
+	}
+}
+
+func TestLookupMount(t *testing.T) {
+	var lookups int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/sys/internal/ui/mounts/secret/app":
+			lookups++
+			fmt.Fprint(w, `{"data": {"path": "secret/", "type": "kv", "options": {"version": "2"}}}`)
+		case "/v1/sys/internal/ui/mounts/denied/app":
+			lookups++
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors": ["permission denied"]}`)
+		case "/v1/secret/data/app":
+			fmt.Fprint(w, `{"data": {"data": {"user": "app"}, "metadata": {"version": 3}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	auth := &countingAuth{}
+	client, err := NewClientWithAuth(Config{Address: server.URL}, auth)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		secret, err := client.ReadSecret(ctx, "secret/app")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if secret.KVVersion != 2 || secret.Version != 3 || secret.Data["user"] != "app" {
+			t.Errorf("unexpected secret %+v", secret)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("expected the mount to be looked up once, got %d lookups", lookups)
+	}
+
+	// A lookup refused with a fresh token is cached and not retried
+	lookups = 0
+	for i := 0; i < 2; i++ {
+		if _, err := client.ReadSecret(ctx, "denied/app"); !errors.Is(err, ErrMountLookup) {
+			t.Errorf("expected ErrMountLookup, got %v", err)
+		}
+	}
+	if _, err := client.ListSecrets(ctx, "denied/app", 10); !errors.Is(err, ErrMountLookup) {
+		t.Errorf("expected ErrMountLookup, got %v", err)
+	}
+	if lookups != 2 {
+		t.Errorf("expected the failed lookup to be retried once after logging in again, then cached, got %d lookups", lookups)
+	}
+	if auth.logins != 2 {
+		t.Errorf("expected a second login for the refused lookup, got %d", auth.logins)
+	}
+}
+
+// sequentialAuth issues a new token on every login and can be made to fail
+type sequentialAuth struct {
+	logins int
+	fail   bool
+}
+
+func (a *sequentialAuth) Login(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	if a.fail {
+		return nil, errors.New("vault is unavailable")
+	}
+	a.logins++
+	return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: fmt.Sprintf("token-%d", a.logins), LeaseDuration: 3600}}, nil
+}
+
+func TestLookupMountTransientErrors(t *testing.T) {
+	const mountResponse = `{"data": {"path": "secret/", "type": "kv", "options": {"version": "2"}}}`
+
+	tests := []struct {
+		name string
+		// respond answers the n-th mount lookup (from 1) made with token
+		respond func(w http.ResponseWriter, n int, token string)
+		// failLogin makes the first login fail
+		failLogin bool
+	}{
+		{
+			name: "revoked token",
+			respond: func(w http.ResponseWriter, n int, token string) {
+				if token == "token-1" {
+					w.WriteHeader(http.StatusForbidden)
+					fmt.Fprint(w, `{"errors": ["permission denied"]}`)
+					return
+				}
+				fmt.Fprint(w, mountResponse)
+			},
+		},
+		{
+			name: "server error",
+			respond: func(w http.ResponseWriter, n int, token string) {
+				if n == 1 {
+					w.WriteHeader(http.StatusInternalServerError)
+					fmt.Fprint(w, `{"errors": ["internal error"]}`)
+					return
+				}
+				fmt.Fprint(w, mountResponse)
+			},
+		},
+		{
+			name: "connection error",
+			respond: func(w http.ResponseWriter, n int, token string) {
+				if n == 1 {
+					conn, _, _ := w.(http.Hijacker).Hijack()
+					conn.Close()
+					return
+				}
+				fmt.Fprint(w, mountResponse)
+			},
+		},
+		{
+			name:      "failed login",
+			failLogin: true,
+			respond: func(w http.ResponseWriter, n int, token string) {
+				fmt.Fprint(w, mountResponse)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lookups int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				lookups++
+				tt.respond(w, lookups, r.Header.Get("X-Vault-Token"))
+			}))
+			defer server.Close()
+
+			auth := &sequentialAuth{fail: tt.failLogin}
+			client, err := NewClientWithAuth(Config{Address: server.URL}, auth)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			client.client.SetMaxRetries(0)
+			ctx := context.Background()
+
+			// A revoked token is replaced right away; other failures are
+			// returned, but not cached
+			_, err = client.LookupMount(ctx, "secret/app")
+			if revoked := tt.name == "revoked token"; revoked != (err == nil) {
+				t.Fatalf("unexpected result of the first lookup: %v", err)
+			}
+			auth.fail = false
+
+			mount, err := client.LookupMount(ctx, "secret/app")
+			if err != nil {
+				t.Fatalf("expected the failure not to be cached, got %v", err)
+			}
+			if mount.Path != "secret/" || mount.KVVersion != 2 {
+				t.Errorf("unexpected mount %+v", mount)
+			}
+		})
+	}
+}
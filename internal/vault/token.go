@@ -0,0 +1,142 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// TokenInfo describes the state of a client's Vault token
+type TokenInfo struct {
+	// TTL is the remaining time to live. Zero means the token never expires.
+	TTL time.Duration
+
+	// ExpireTime is when the token expires. Zero means the token never expires.
+	ExpireTime time.Time
+
+	// Renewable reports whether the token can be renewed
+	Renewable bool
+
+	// Renewed is set when the token was renewed during this check
+	Renewed bool
+
+	// Reauthenticated is set when a new token was obtained through the auth method
+	Reauthenticated bool
+}
+
+// ManageToken looks up the client's token and keeps it alive: once less than
+// half of its creation TTL remains it is renewed, and when renewal is not
+// possible (not renewable, max TTL reached or token revoked) the client logs
+// in again with its auth method. Clients using a static token can only be
+// renewed; an error is returned when such a token is about to expire.
+func (c *Client) ManageToken(ctx context.Context) (*TokenInfo, error) {
+	if err := c.Authenticate(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lookup, err := c.client.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil {
+		if c.auth == nil {
+			return nil, fmt.Errorf("failed to look up vault token: %w", err)
+		}
+		// The token was most likely revoked, get a new one
+		return c.reauthenticateLocked(ctx)
+	}
+
+	info, creationTTL, err := tokenInfoFromLookup(lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	// Non-expiring tokens and tokens with enough TTL left need no action
+	if info.TTL == 0 || info.TTL > creationTTL/2 {
+		return info, nil
+	}
+
+	var renewErr error
+	if info.Renewable {
+		renewed, err := c.client.Auth().Token().RenewSelfWithContext(ctx, 0)
+		if err == nil && renewed != nil && renewed.Auth != nil {
+			ttl := time.Duration(renewed.Auth.LeaseDuration) * time.Second
+			c.setTokenTTLLocked(ttl)
+
+			// A renewal that cannot extend the TTL means the max TTL is close
+			if ttl > creationTTL/2 || c.auth == nil {
+				return &TokenInfo{
+					TTL:        ttl,
+					ExpireTime: time.Now().Add(ttl),
+					Renewable:  renewed.Auth.Renewable,
+					Renewed:    true,
+				}, nil
+			}
+		} else if err != nil {
+			renewErr = fmt.Errorf("failed to renew vault token: %w", err)
+		}
+	}
+
+	if c.auth != nil {
+		return c.reauthenticateLocked(ctx)
+	}
+
+	if renewErr != nil {
+		return info, renewErr
+	}
+	return info, fmt.Errorf("vault token expires in %s and cannot be renewed", info.TTL)
+}
+
+// reauthenticateLocked replaces the token by logging in again. c.mu must be held.
+func (c *Client) reauthenticateLocked(ctx context.Context) (*TokenInfo, error) {
+	secret, err := c.loginLocked(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &TokenInfo{
+		Renewable:       secret.Auth.Renewable,
+		Reauthenticated: true,
+	}
+	if secret.Auth.LeaseDuration > 0 {
+		info.TTL = time.Duration(secret.Auth.LeaseDuration) * time.Second
+		info.ExpireTime = time.Now().Add(info.TTL)
+	}
+
+	return info, nil
+}
+
+// tokenInfoFromLookup converts a token/lookup-self response into a TokenInfo
+// and returns the TTL the token was created with
+func tokenInfoFromLookup(lookup *vault.Secret) (*TokenInfo, time.Duration, error) {
+	if lookup == nil || lookup.Data == nil {
+		return nil, 0, fmt.Errorf("empty response from token lookup")
+	}
+
+	ttl, err := lookup.TokenTTL()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse token ttl: %w", err)
+	}
+
+	renewable, err := lookup.TokenIsRenewable()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse token renewable flag: %w", err)
+	}
+
+	creationTTL := ttl
+	if raw, ok := lookup.Data["creation_ttl"].(json.Number); ok {
+		if seconds, err := raw.Int64(); err == nil && seconds > 0 {
+			creationTTL = time.Duration(seconds) * time.Second
+		}
+	}
+
+	info := &TokenInfo{TTL: ttl, Renewable: renewable}
+	if ttl > 0 {
+		info.ExpireTime = time.Now().Add(ttl)
+	}
+
+	return info, creationTTL, nil
+}
@@ -24,5 +24,15 @@ if [ -f "config/crd/secrets.tim.operator_timsecretconfigs.yaml" ]; then
     echo "✅ Generated timsecretconfig-crd.yaml"
 fi
 
+if [ -f "config/crd/secrets.tim.operator_clustertimsecretconfigs.yaml" ]; then
+    mv config/crd/secrets.tim.operator_clustertimsecretconfigs.yaml config/crd/clustertimsecretconfig-crd.yaml
+    echo "✅ Generated clustertimsecretconfig-crd.yaml"
+fi
+
+if [ -f "config/crd/secrets.tim.operator_timsecretdirectories.yaml" ]; then
+    mv config/crd/secrets.tim.operator_timsecretdirectories.yaml config/crd/timsecretdirectory-crd.yaml
+    echo "✅ Generated timsecretdirectory-crd.yaml"
+fi
+
 echo "✅ CRDs generated successfully!"
 
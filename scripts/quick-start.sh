@@ -29,6 +29,8 @@ echo ""
 echo "📦 Installing Custom Resource Definitions..."
 kubectl apply -f config/crd/timsecret-crd.yaml
 kubectl apply -f config/crd/timsecretconfig-crd.yaml
+kubectl apply -f config/crd/clustertimsecretconfig-crd.yaml
+kubectl apply -f config/crd/timsecretdirectory-crd.yaml
 echo "✅ CRDs installed"
 echo ""
 
@@ -46,6 +48,12 @@ kubectl apply -f config/rbac/role_binding.yaml
 echo "✅ RBAC resources installed"
 echo ""
 
+echo "📦 Installing admission webhooks..."
+kubectl apply -f config/webhook/service.yaml
+kubectl apply -f config/webhook/manifests.yaml
+echo "✅ Admission webhooks installed"
+echo ""
+
 # Step 4: Check if Docker image exists
 IMAGE_NAME="timvault-operator:latest"
 echo "⚠️  Note: Make sure you have built the Docker image: $IMAGE_NAME"
@@ -53,6 +53,14 @@ kubectl create namespace "$NAMESPACE" --dry-run=client -o yaml | kubectl apply -
 echo "✅ Namespace created"
 echo ""
 
+# Store the Vault token in a Secret
+echo "📦 Creating Vault token Secret..."
+kubectl create secret generic vault-token -n "$NAMESPACE" \
+    --from-literal=token="$VAULT_TOKEN" \
+    --dry-run=client -o yaml | kubectl apply -f -
+echo "✅ Secret created"
+echo ""
+
 # Create TimSecretConfig
 echo "📦 Creating TimSecretConfig..."
 cat <<EOF | kubectl apply -f -
@@ -63,7 +71,9 @@ metadata:
   namespace: $NAMESPACE
 spec:
   vaultURL: "$VAULT_URL"
-  vaultToken: "$VAULT_TOKEN"
+  tokenSecretRef:
+    name: vault-token
+    key: token
 EOF
 echo "✅ TimSecretConfig created"
 echo ""
@@ -23,7 +23,7 @@ if [[ ! $REPLY =~ ^[Yy]$ ]]; then
     exit 0
 fi
 
-# Step 1: Delete all TimSecret resources
+# Step 1: Delete all TimSecret resources while the operator can still apply their deletionPolicy
 echo "🗑️  Deleting TimSecret resources..."
 kubectl delete timsecrets --all --all-namespaces || true
 echo "✅ TimSecret resources deleted"
@@ -32,6 +32,8 @@ echo ""
 # Step 2: Delete operator deployment
 echo "🗑️  Deleting operator deployment..."
 kubectl delete -f config/manager/deployment.yaml || true
+kubectl delete -f config/webhook/manifests.yaml || true
+kubectl delete -f config/webhook/service.yaml || true
 echo "✅ Operator deployment deleted"
 echo ""
 
@@ -47,6 +49,8 @@ echo ""
 echo "🗑️  Deleting Custom Resource Definitions..."
 kubectl delete -f config/crd/timsecret-crd.yaml || true
 kubectl delete -f config/crd/timsecretconfig-crd.yaml || true
+kubectl delete -f config/crd/clustertimsecretconfig-crd.yaml || true
+kubectl delete -f config/crd/timsecretdirectory-crd.yaml || true
 echo "✅ CRDs deleted"
 echo ""
 